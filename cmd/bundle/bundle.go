@@ -0,0 +1,67 @@
+package bundle
+
+import (
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+)
+
+var Command = &cobra.Command{
+	Use:   "bundle",
+	Short: "Pack a version for offline installs",
+	Long: `Pack the manifest, release metadata, checksums and image list
+of a version into one archive, for install --bundle and update --bundle
+in clusters without network access`,
+}
+
+var createCommand = &cobra.Command{
+	Use:   "create <version> FLAG",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create the offline bundle of a version",
+	Long:  `Create the offline bundle of a version, "latest" for the latest release`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		verify, err := release.GetVerifyOptions(cmd.Flags())
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		output, err = pkg.CreateBundle(args[0], output, verify)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		t.PrintSuccessOneLine("Bundle Created: %s", output)
+		t.LineEnd()
+	},
+}
+
+var inspectCommand = &cobra.Command{
+	Use:   "inspect <bundle>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Verify a bundle and show its version and images",
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		b, err := release.ReadBundle(args[0])
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		t.PrintSuccessOneLine("Checksums Verified: %s", args[0])
+		t.LineEnd()
+		t.PrintInfoOneLine("Version: %s [%s@%s]", b.Release.Name, b.Release.TagName, b.Release.TargetCommitish)
+		t.LineEnd()
+		for _, image := range b.Images {
+			t.PrintInfoOneLine("Image: %s", image)
+			t.LineEnd()
+		}
+	},
+}
+
+func init() {
+	createCommand.Flags().StringP("output", "o", "", "the bundle file path, funceasy-<version>.tar.gz by default")
+	createCommand.Flags().AddFlagSet(release.VerifyFlags())
+	Command.AddCommand(createCommand, inspectCommand)
+}
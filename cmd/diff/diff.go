@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// the exit codes of diff(1): no differences, differences, trouble
+const (
+	exitDifferent = 1
+	exitError     = 2
+)
+
+var Command = &cobra.Command{
+	Use:   "diff <release-name> <version>",
+	Short: "show what an update changes in kubernetes",
+	Long: `diff command compares the live objects of a release with the
+objects an update to the version or file leaves, and prints a unified
+diff for each object that differs. The server populated fields are
+ignored. It exits with 0 without differences, 1 with differences and
+2 on errors`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		fail := func(a ...interface{}) {
+			t.PrintErrorOneLine(a...)
+			os.Exit(exitError)
+		}
+		// the version of the install section is the installed one, diff takes the target as an argument
+		values, _, err := pkg.LoadInstallValues(cmd.Flags(), false)
+		if err != nil {
+			fail(err)
+		}
+		showSecrets, err := cmd.Flags().GetBool("show-secrets")
+		if err != nil {
+			fail(err)
+		}
+		if len(args) == 0 {
+			fail("Need argument - release name")
+		}
+		releaseName := args[0]
+		args = args[1:]
+		options, err := pkg.GetUpdateOptions(cmd.Flags(), releaseName, values)
+		if err != nil {
+			fail(err)
+		}
+		options.ShowSecrets = showSecrets
+		fileByte, err := pkg.LoadInstallManifest(cmd.Flags(), args)
+		if err != nil {
+			fail(err)
+		}
+		different, err := pkg.DiffFuncEasyResources(fileByte, options)
+		if err != nil {
+			fail(err)
+		}
+		if different {
+			os.Exit(exitDifferent)
+		}
+	},
+}
+
+func init() {
+	Command.Flags().AddFlagSet(pkg.UpdateFlags())
+	Command.Flags().Bool("show-secrets", false, "print the Secret data instead of digests")
+}
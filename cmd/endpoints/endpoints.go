@@ -0,0 +1,34 @@
+package endpoints
+
+import (
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+)
+
+var Command = &cobra.Command{
+	Use:   "endpoints [release-name]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Show the URLs of the FuncEasy website, API and gateway",
+	Long: `Show the URLs of the FuncEasy website, API and gateway,
+resolved from the Ingress hosts, the LoadBalancer addresses or
+the node addresses, see install --expose`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		releaseName := util.DefaultReleaseName
+		if len(args) == 1 {
+			releaseName = args[0]
+		}
+		endpoints, err := pkg.GetEndpoints(releaseName)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		if len(endpoints) == 0 {
+			t.PrintWarnOneLine("No Exposed Services Found")
+			t.LineEnd()
+			return
+		}
+		pkg.PrintEndpoints(endpoints)
+	},
+}
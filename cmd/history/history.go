@@ -0,0 +1,54 @@
+package history
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var Command = &cobra.Command{
+	Use:   "history [release-name]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Show the install and update revisions of a release",
+	Long: `Show the install and update revisions recorded for a release,
+with the manifest version, storage mode and CLI version of each`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		releaseName := util.DefaultReleaseName
+		if len(args) == 1 {
+			releaseName = args[0]
+		}
+		records, err := pkg.GetReleaseHistory(releaseName)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		if len(records) == 0 {
+			t.PrintWarnOneLine("No History Found: %s", releaseName)
+			t.LineEnd()
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "REVISION\tUPDATED\tACTION\tVERSION\tSTORAGE\tCLI\tOBJECTS\tDIGEST")
+		for _, record := range records {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s:%s\t%s\t%d\t%.19s\n",
+				record.Revision,
+				record.Timestamp.Local().Format(time.RFC3339),
+				record.Action,
+				record.Version,
+				record.PVType,
+				record.PathOrClass,
+				record.CLIVersion,
+				len(record.Objects),
+				record.ManifestDigest)
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+}
@@ -0,0 +1,74 @@
+package images
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+)
+
+var Command = &cobra.Command{
+	Use:   "images",
+	Short: "Inspect the images of a release manifest",
+	Long:  `Inspect the images of a release manifest, to mirror them to a private registry`,
+}
+
+var listCommand = &cobra.Command{
+	Use:   "list <version> FLAG",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "List the images a release manifest references",
+	Long: `List every container and init container image a release manifest references,
+and the images of the Jobs install runs to prepare the local volumes and
+to check an external database.
+Use --image-registry to print the names the images get in the mirror registry`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		filePath, err := cmd.Flags().GetString("file")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		verify, err := release.GetVerifyOptions(cmd.Flags())
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		registry, err := cmd.Flags().GetString("image-registry")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		var fileByte []byte
+		if filePath != "" && len(args) == 0 {
+			fileByte, err = ioutil.ReadFile(filePath)
+			if err != nil {
+				t.PrintErrorOneLineWithExit("Read Yaml File Error: ", err)
+			}
+		} else if filePath == "" && len(args) == 1 {
+			version := args[0]
+			r, found := release.GetVersionRelease(version)
+			if !found {
+				t.PrintErrorOneLineWithExit("Version Not Found: ", version)
+			}
+			fileByte, err = release.DownloadManifest(r, verify)
+			if err != nil {
+				t.PrintErrorOneLineWithExit(err)
+			}
+		} else {
+			t.PrintErrorOneLineWithExit("Use arg <version> or flags [--file] ")
+		}
+		images, err := pkg.ListReleaseImages(fileByte, registry)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		for _, image := range images {
+			fmt.Println(image)
+		}
+	},
+}
+
+func init() {
+	listCommand.Flags().StringP("file", "f", "", "the yaml file path to list")
+	listCommand.Flags().AddFlagSet(release.VerifyFlags())
+	listCommand.Flags().String("image-registry", "", "print the image names in this mirror registry")
+	Command.AddCommand(listCommand)
+}
@@ -17,80 +17,176 @@ package install
 
 import (
 	"github.com/funceasy/funceasy-cli/pkg"
-	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"github.com/funceasy/funceasy-cli/pkg/util"
 	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
 	"github.com/spf13/cobra"
-	"io/ioutil"
-	"k8s.io/client-go/util/homedir"
-	"path/filepath"
-	"regexp"
 )
 
 // generateCmd represents the generate command
 var Command = &cobra.Command{
-	Use:   "install <version> FLAG",
+	Use:   "install <release-name> <version> FLAG",
 	Short: "install FuncEasy in kubernetes",
 	Long: `install command allows user to install FuncEasy CRD 
-and other module working for FuncEasy`,
+and other module working for FuncEasy. The release name prefixes
+the installed objects so several releases can coexist, use
+"funceasy" to keep the manifest names`,
 	Run: func(cmd *cobra.Command, args []string) {
 		t := terminal.NewTerminalPrint()
-		filePath, err := cmd.Flags().GetString("file")
+		values, version, err := pkg.LoadInstallValues(cmd.Flags(), true)
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
 		}
-		local, err := cmd.Flags().GetString("local")
+		filePath, err := cmd.Flags().GetString("file")
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
 		}
-		sc, err := cmd.Flags().GetString("storage-class")
+		interactive, err := cmd.Flags().GetBool("interactive")
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
 		}
-		if filePath == "" && len(args) != 1 {
-			t.PrintErrorOneLineWithExit("Need exactly one argument - version")
+		bundlePath, err := cmd.Flags().GetString("bundle")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
 		}
-		var fileByte []byte
-		if filePath != "" && len(args) == 0 {
-			fileByte, err = ioutil.ReadFile(filePath)
+		if interactive {
+			if len(args) == 0 {
+				releaseName, err := t.PromptRequired("Release name", util.DefaultReleaseName)
+				if err != nil {
+					t.PrintErrorOneLineWithExit(err)
+				}
+				args = append(args, releaseName)
+			}
+			current := make(map[string]string)
+			for _, name := range pkg.WizardFlags {
+				current[name] = cmd.Flags().Lookup(name).Value.String()
+			}
+			answers, err := pkg.InstallWizard(current, filePath == "" && bundlePath == "" && len(args) == 1)
 			if err != nil {
-				t.PrintErrorOneLineWithExit("Read Yaml File Error: ", err)
+				t.PrintErrorOneLineWithExit(err)
 			}
-		} else if filePath == "" && len(args) == 1 {
-			version := args[0]
-			downloadUrl := ""
-			r, _ := regexp.Compile("^(.+).yaml$")
-			if version == "latest" {
-				release := release.GetLatestRelease()
-				for _, asset := range release.Assets {
-					if r.MatchString(asset.Name) {
-						downloadUrl = asset.Download
+			if answers[pkg.WizardVersionKey] != "" {
+				version = answers[pkg.WizardVersionKey]
+			}
+			saved := make(map[string]interface{})
+			for name, answer := range answers {
+				if name != pkg.WizardVersionKey {
+					err = cmd.Flags().Set(name, answer)
+					if err != nil {
+						t.PrintErrorOneLineWithExit(err)
 					}
 				}
-			} else {
-				list := release.GetRelease()
-				for _, item := range list {
-					if item.Name == version {
-						for _, asset := range item.Assets {
-							if r.MatchString(asset.Name) {
-								downloadUrl = asset.Download
-							}
-						}
-					}
+				if answer == "" {
+					// null drops the key a loaded values file had
+					saved[name] = nil
+				} else {
+					saved[name] = answer
+				}
+			}
+			values = util.MergeValues(values, util.Values{util.InstallValuesKey: saved})
+		}
+		dryRun, err := cmd.Flags().GetString("dry-run")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		showSecrets, err := cmd.Flags().GetBool("show-secrets")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		resume, err := cmd.Flags().GetBool("resume")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		noRollback, err := cmd.Flags().GetBool("no-rollback")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		wait, err := cmd.Flags().GetBool("wait")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		historyMax, err := cmd.Flags().GetInt("history-max")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		skipPreflight, err := cmd.Flags().GetBool("skip-preflight")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		if len(args) == 0 {
+			t.PrintErrorOneLineWithExit("Need argument - release name")
+		}
+		releaseName := args[0]
+		args = args[1:]
+		if version != "" && filePath == "" && bundlePath == "" && len(args) == 0 {
+			args = append(args, version)
+		}
+		if filePath == "" && bundlePath == "" && len(args) != 1 {
+			t.PrintErrorOneLineWithExit("Need exactly one argument - version")
+		}
+		fileByte, err := pkg.LoadInstallManifest(cmd.Flags(), args)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		options, err := pkg.GetInstallOptions(cmd.Flags(), releaseName, values)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		options.DryRun = dryRun
+		options.Output = output
+		options.ShowSecrets = showSecrets
+		options.Resume = resume
+		options.NoRollback = noRollback
+		options.Wait = wait
+		options.Timeout = timeout
+		options.HistoryMax = historyMax
+		if interactive {
+			confirmed := true
+			if dryRun == pkg.DryRunNone {
+				err = pkg.PrintInstallSummary(fileByte, options)
+				if err != nil {
+					t.PrintErrorOneLineWithExit(err)
+				}
+				confirmed, err = t.Confirm("Install", true)
+				if err != nil {
+					t.PrintErrorOneLineWithExit(err)
 				}
 			}
-			if downloadUrl == "" {
-				t.PrintErrorOneLineWithExit("Version Not Found: ", version)
+			valuesPath, err := t.Prompt("Save the answers to the values file, - to skip", "funceasy-values.yaml")
+			if err != nil {
+				t.PrintErrorOneLineWithExit(err)
+			}
+			if valuesPath != "-" {
+				err = util.SaveValuesFile(valuesPath, values)
+				if err != nil {
+					t.PrintErrorOneLineWithExit(err)
+				}
+				t.PrintSuccessOneLine("Answers Saved, install again with --values %s", valuesPath)
+				t.LineEnd()
+			}
+			if !confirmed {
+				t.PrintWarnOneLine("Install Cancelled")
+				t.LineEnd()
+				return
 			}
-			fileByte = release.Download(downloadUrl)
-		} else {
-			t.PrintErrorOneLineWithExit("Use arg <version> or flags [--file] ")
 		}
-		if local != "" && sc == "" {
-			err = pkg.DeployFuncEasyResources(fileByte, "Local", local)
-		} else if local == "" && sc != "" {
-			err = pkg.DeployFuncEasyResources(fileByte, "StorageClass", sc)
+		if dryRun != pkg.DryRunNone {
+			err = pkg.DryRunFuncEasyResources(fileByte, options)
 		} else {
-			t.PrintErrorOneLineWithExit("Only one type: Local or StorageClass")
+			if !skipPreflight {
+				err = pkg.Preflight(fileByte, options)
+				if err != nil {
+					t.PrintErrorOneLineWithExit(err)
+				}
+			}
+			err = pkg.DeployFuncEasyResources(fileByte, options)
 		}
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
@@ -99,13 +195,16 @@ and other module working for FuncEasy`,
 }
 
 func init() {
-	var mountPath string
-	if home := homedir.HomeDir(); home != "" {
-		mountPath = filepath.Join(home, "mnt", "funceasy-data")
-	} else {
-		mountPath = "/mnt/funceasy-data"
-	}
-	Command.Flags().StringP("file", "f", "", "the yaml file path to install")
-	Command.Flags().StringP("local", "l", mountPath, "the local mount path")
-	Command.Flags().StringP("storage-class", "s", "", "the PVC StorageClass name")
+	Command.Flags().AddFlagSet(pkg.InstallFlags())
+	Command.Flags().String("dry-run", pkg.DryRunNone, "print the objects without creating them: client or server")
+	Command.Flags().Lookup("dry-run").NoOptDefVal = pkg.DryRunClient
+	Command.Flags().StringP("output", "o", "yaml", "the dry run output format: yaml or json")
+	Command.Flags().Bool("show-secrets", false, "print the Secret data in the dry run output")
+	Command.Flags().Bool("resume", false, "continue a failed install, updating the objects it left behind")
+	Command.Flags().Bool("no-rollback", false, "keep the objects of a failed install for debugging, continue with --resume")
+	Command.Flags().Bool("wait", true, "wait for every Deployment to roll out, --wait=false returns once the objects are created")
+	Command.Flags().Duration("timeout", pkg.DefaultRolloutTimeout, "how long to wait for the rollouts, dependencies included")
+	Command.Flags().Int("history-max", pkg.DefaultHistoryMax, "the number of revisions kept in the release history, 0 keeps them all")
+	Command.Flags().Bool("interactive", false, "ask for the version, storage, profile, exposure and database, then save the answers as a values file")
+	Command.Flags().Bool("skip-preflight", false, "install without running the preflight checks first")
 }
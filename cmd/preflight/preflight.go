@@ -0,0 +1,70 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package preflight
+
+import (
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+)
+
+var Command = &cobra.Command{
+	Use:   "preflight <release-name> <version> FLAG",
+	Short: "check the cluster before installing FuncEasy",
+	Long: `preflight command checks the server version, the APIs and
+the permissions the manifest needs, with the namespace, the release
+record Secrets and the Jobs the install creates, the storage, the
+requested NodePorts and the node capacity. install runs it first`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		values, version, err := pkg.LoadInstallValues(cmd.Flags(), true)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		filePath, err := cmd.Flags().GetString("file")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		bundlePath, err := cmd.Flags().GetString("bundle")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		if len(args) == 0 {
+			t.PrintErrorOneLineWithExit("Need argument - release name")
+		}
+		releaseName := args[0]
+		args = args[1:]
+		if version != "" && filePath == "" && bundlePath == "" && len(args) == 0 {
+			args = append(args, version)
+		}
+		fileByte, err := pkg.LoadInstallManifest(cmd.Flags(), args)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		options, err := pkg.GetInstallOptions(cmd.Flags(), releaseName, values)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		err = pkg.Preflight(fileByte, options)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+	},
+}
+
+func init() {
+	Command.Flags().AddFlagSet(pkg.InstallFlags())
+}
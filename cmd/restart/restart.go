@@ -2,17 +2,38 @@ package restart
 
 import (
 	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
 	"github.com/spf13/cobra"
 )
 
 var Command = &cobra.Command{
-	Use:   "restart",
+	Use:   "restart [release-name]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "Restart FuncEasy Pods and Services in Kubernetes",
 	Long: `Restart FuncEasy Pods and Services in Kubernetes`,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkg.Restart()
+		t := terminal.NewTerminalPrint()
+		releaseName := util.DefaultReleaseName
+		if len(args) == 1 {
+			releaseName = args[0]
+		}
+		wait, err := cmd.Flags().GetBool("wait")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		err = pkg.Restart(releaseName, wait, timeout)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
 	},
 }
 
 func init() {
+	Command.Flags().Bool("wait", true, "wait for the restarted Deployments to roll out, --wait=false returns once they are restarted")
+	Command.Flags().Duration("timeout", pkg.DefaultRolloutTimeout, "how long to wait for the rollouts")
 }
@@ -17,12 +17,20 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/funceasy/funceasy-cli/cmd/bundle"
+	"github.com/funceasy/funceasy-cli/cmd/diff"
+	"github.com/funceasy/funceasy-cli/cmd/endpoints"
 	"github.com/funceasy/funceasy-cli/cmd/generate"
+	"github.com/funceasy/funceasy-cli/cmd/history"
+	"github.com/funceasy/funceasy-cli/cmd/images"
 	"github.com/funceasy/funceasy-cli/cmd/install"
+	"github.com/funceasy/funceasy-cli/cmd/preflight"
 	"github.com/funceasy/funceasy-cli/cmd/restart"
 	"github.com/funceasy/funceasy-cli/cmd/status"
+	"github.com/funceasy/funceasy-cli/cmd/uninstall"
 	"github.com/funceasy/funceasy-cli/cmd/update"
 	"github.com/funceasy/funceasy-cli/cmd/version"
+	"github.com/funceasy/funceasy-cli/pkg"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -50,7 +58,14 @@ func Execute() {
 		version.Command,
 		update.Command,
 		status.Command,
-		restart.Command)
+		restart.Command,
+		history.Command,
+		uninstall.Command,
+		images.Command,
+		bundle.Command,
+		preflight.Command,
+		endpoints.Command,
+		diff.Command)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -65,6 +80,13 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.funceasy-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&pkg.KubeConfig.KubeConfig, "kubeconfig", "", "path to the kubeconfig file (default is $KUBECONFIG or $HOME/.kube/config)")
+	rootCmd.PersistentFlags().StringVar(&pkg.KubeConfig.Context, "context", "", "the kubeconfig context to use")
+	rootCmd.PersistentFlags().StringVar(&pkg.KubeConfig.Cluster, "cluster", "", "the kubeconfig cluster to use")
+	rootCmd.PersistentFlags().StringVar(&pkg.KubeConfig.User, "user", "", "the kubeconfig user to use")
+	rootCmd.PersistentFlags().StringVar(&pkg.KubeConfig.Impersonate, "as", "", "username to impersonate for the operation")
+	rootCmd.PersistentFlags().StringVarP(&pkg.KubeConfig.Namespace, "namespace", "n", pkg.DefaultNamespace, "the namespace FuncEasy is installed in, or $FUNCEASY_NAMESPACE")
+	_ = viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -89,10 +111,13 @@ func initConfig() {
 		viper.SetConfigName(".funceasy-cli")
 	}
 
+	// only the FUNCEASY_ variables, a NAMESPACE set by CI pods must not retarget the release
+	viper.SetEnvPrefix("FUNCEASY")
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
+	pkg.KubeConfig.Namespace = viper.GetString("namespace")
 }
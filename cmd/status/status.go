@@ -2,16 +2,27 @@ package status
 
 import (
 	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
 	"github.com/spf13/cobra"
 )
 
 // generateCmd represents the generate command
 var Command = &cobra.Command{
-	Use:   "status",
+	Use:   "status [release-name]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "Show FuncEasy Pods Status in Kubernetes",
 	Long: `Show FuncEasy Pods Status in Kubernetes`,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkg.GetResourceStatus()
+		t := terminal.NewTerminalPrint()
+		releaseName := util.DefaultReleaseName
+		if len(args) == 1 {
+			releaseName = args[0]
+		}
+		err := pkg.GetResourceStatus(releaseName)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
 	},
 }
 
@@ -0,0 +1,53 @@
+package uninstall
+
+import (
+	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var Command = &cobra.Command{
+	Use:   "uninstall [release-name] FLAG",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "uninstall FuncEasy from kubernetes",
+	Long: `uninstall command deletes the objects recorded for a release
+in reverse dependency order and waits until they are gone. The
+data directories of the local volumes are removed too, on their node
+with a Job, unless --keep-data`,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := terminal.NewTerminalPrint()
+		keepData, err := cmd.Flags().GetBool("keep-data")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		keepCRDs, err := cmd.Flags().GetBool("keep-crds")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		releaseName := util.DefaultReleaseName
+		if len(args) == 1 {
+			releaseName = args[0]
+		}
+		err = pkg.UninstallFuncEasyResources(&pkg.UninstallOptions{
+			ReleaseName: releaseName,
+			KeepData:    keepData,
+			KeepCRDs:    keepCRDs,
+			Timeout:     timeout,
+		})
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+	},
+}
+
+func init() {
+	Command.Flags().Bool("keep-data", false, "keep the PVCs, PVs and local data directories")
+	Command.Flags().Bool("keep-crds", false, "keep the CustomResourceDefinitions and their resources")
+	Command.Flags().Duration("timeout", 5*time.Minute, "the time to wait for the deletion to complete")
+}
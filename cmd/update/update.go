@@ -2,67 +2,72 @@ package update
 
 import (
 	"github.com/funceasy/funceasy-cli/pkg"
-	"github.com/funceasy/funceasy-cli/pkg/util/release"
 	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
 	"github.com/spf13/cobra"
-	"io/ioutil"
-	"regexp"
 )
 
 var Command = &cobra.Command{
-	Use:   "update <version>",
+	Use:   "update <release-name> <version>",
 	Short: "update FuncEasy in kubernetes",
 	Long: `update command allows user to update FuncEasy Resources 
-to a available version`,
+to a available version. The objects are changed in place with a
+server-side apply, the fields other tools changed are reported as
+conflicts. The CRDs are replaced so removed schema fields are dropped`,
 	Run: func(cmd *cobra.Command, args []string) {
 		t := terminal.NewTerminalPrint()
-		filePath, err := cmd.Flags().GetString("file")
+		// the version of the install section is the installed one, update takes the target as an argument
+		values, _, err := pkg.LoadInstallValues(cmd.Flags(), false)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		wait, err := cmd.Flags().GetBool("wait")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		historyMax, err := cmd.Flags().GetInt("history-max")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		allowCRDBreakingChanges, err := cmd.Flags().GetBool("allow-crd-breaking-changes")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		forceConflicts, err := cmd.Flags().GetBool("force-conflicts")
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		if len(args) == 0 {
+			t.PrintErrorOneLineWithExit("Need argument - release name")
+		}
+		releaseName := args[0]
+		args = args[1:]
+		options, err := pkg.GetUpdateOptions(cmd.Flags(), releaseName, values)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		options.Wait = wait
+		options.Timeout = timeout
+		options.HistoryMax = historyMax
+		options.AllowCRDBreakingChanges = allowCRDBreakingChanges
+		options.ForceConflicts = forceConflicts
+		currentVersion, err := pkg.GetCurrentVersion(releaseName)
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
 		}
-		currentVersion := pkg.GetCurrentVersion()
 		if currentVersion == "" {
 			t.PrintWarnOneLine("Not Install")
 			t.LineEnd()
 			return
 		}
-		var fileByte []byte
-		if filePath != "" && len(args) == 0 {
-			fileByte, err = ioutil.ReadFile(filePath)
-			if err != nil {
-				t.PrintErrorOneLineWithExit("Read Yaml File Error: ", err)
-			}
-		} else if filePath == "" && len(args) == 1 {
-			version := args[0]
-			downloadUrl := ""
-			r, _ := regexp.Compile("^(.+).yaml$")
-			if version == "latest" {
-				release := release.GetLatestRelease()
-				for _, asset := range release.Assets {
-					if r.MatchString(asset.Name) {
-						downloadUrl = asset.Download
-					}
-				}
-			} else {
-				list := release.GetRelease()
-				for _, item := range list {
-					if item.Name == version {
-						for _, asset := range item.Assets {
-							if r.MatchString(asset.Name) {
-								downloadUrl = asset.Download
-							}
-						}
-					}
-				}
-			}
-			if downloadUrl == "" {
-				t.PrintErrorOneLineWithExit("Version Not Found: ", version)
-			}
-			fileByte = release.Download(downloadUrl)
-		} else {
-			t.PrintErrorOneLineWithExit("Use arg <version> or flags [--file] ")
+		fileByte, err := pkg.LoadInstallManifest(cmd.Flags(), args)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
 		}
-		err = pkg.UpdateFuncEasyResources(fileByte)
+		err = pkg.UpdateFuncEasyResources(fileByte, options)
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
 		}
@@ -70,6 +75,10 @@ to a available version`,
 }
 
 func init() {
-	Command.Flags().StringP("file", "f", "", "the yaml file path to update")
+	Command.Flags().AddFlagSet(pkg.UpdateFlags())
+	Command.Flags().Bool("wait", true, "wait for every Deployment to roll out, --wait=false returns once the objects are applied")
+	Command.Flags().Duration("timeout", pkg.DefaultRolloutTimeout, "how long to wait for the rollouts, dependencies included")
+	Command.Flags().Int("history-max", pkg.DefaultHistoryMax, "the number of revisions kept in the release history, 0 keeps them all")
+	Command.Flags().Bool("allow-crd-breaking-changes", false, "apply CRD changes that remove versions or move the storage version")
+	Command.Flags().Bool("force-conflicts", false, "take over the fields other field managers, such as kubectl, changed since the install")
 }
-
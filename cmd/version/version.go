@@ -3,6 +3,7 @@ package version
 import (
 	"fmt"
 	"github.com/funceasy/funceasy-cli/pkg"
+	"github.com/funceasy/funceasy-cli/pkg/util"
 	"github.com/funceasy/funceasy-cli/pkg/util/release"
 	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
 	"github.com/spf13/cobra"
@@ -10,7 +11,8 @@ import (
 
 // generateCmd represents the generate command
 var Command = &cobra.Command{
-	Use:   "version",
+	Use:   "version [release-name]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "current version",
 	Long: `Show current version. Use inspect to get all available version`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -19,8 +21,15 @@ var Command = &cobra.Command{
 		if err != nil {
 			t.PrintErrorOneLineWithExit(err)
 		}
-		currentVersion := pkg.GetCurrentVersion()
-		if len(args) == 0 && !inspect {
+		releaseName := util.DefaultReleaseName
+		if len(args) == 1 {
+			releaseName = args[0]
+		}
+		currentVersion, err := pkg.GetCurrentVersion(releaseName)
+		if err != nil {
+			t.PrintErrorOneLineWithExit(err)
+		}
+		if !inspect {
 			if currentVersion != "" {
 				t.PrintInfoOneLine("Current Version: %s", currentVersion)
 				t.LineEnd()
@@ -28,7 +37,7 @@ var Command = &cobra.Command{
 				t.PrintWarnOneLine("Not Install")
 				t.LineEnd()
 			}
-		} else if inspect {
+		} else {
 			releases := release.GetRelease()
 			for _, item := range releases {
 				if item.Name == currentVersion {
@@ -38,8 +47,6 @@ var Command = &cobra.Command{
 					fmt.Printf("  %s [%s@%s]\n", item.Name, item.TagName, item.TargetCommitish)
 				}
 			}
-		} else {
-			_ = cmd.Help()
 		}
 	},
 }
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"regexp"
+	"sort"
+)
+
+// FieldManager owns the fields update applies. The API server names the manager of the create
+// and update calls after the user agent, which starts with the same name
+const FieldManager = "funceasy-cli"
+
+var conflictManagerPattern = regexp.MustCompile(`^conflict with "([^"]*)"`)
+
+// UpdateKeeps tells the live objects update leaves as they are: the volumes, their claims
+// and the Secrets of the keys handed out at install
+func UpdateKeeps(obj *unstructured.Unstructured) bool {
+	switch obj.GetKind() {
+	case "PersistentVolume", "PersistentVolumeClaim":
+		return true
+	case "Secret":
+		return obj.GetLabels()["generatedBy"] == "cli"
+	}
+	return false
+}
+
+// applyContent drops the null and empty status fields the typed objects carry, apply would claim them
+func applyContent(obj *unstructured.Unstructured) ([]byte, error) {
+	content := obj.DeepCopy()
+	unstructured.RemoveNestedField(content.Object, "metadata", "resourceVersion")
+	if status, ok := content.Object["status"].(map[string]interface{}); ok && len(status) == 0 {
+		delete(content.Object, "status")
+	}
+	return json.Marshal(removeNulls(content.Object))
+}
+
+func removeNulls(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}:
+		for key, item := range value.(map[string]interface{}) {
+			if item == nil {
+				delete(value.(map[string]interface{}), key)
+			} else {
+				removeNulls(item)
+			}
+		}
+	case []interface{}:
+		for _, item := range value.([]interface{}) {
+			removeNulls(item)
+		}
+	}
+	return value
+}
+
+func applyPatch(c dynamic.ResourceInterface, obj *unstructured.Unstructured, force bool, dryRun bool) (*unstructured.Unstructured, error) {
+	data, err := applyContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	options := metaV1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	}
+	if dryRun {
+		options.DryRun = []string{metaV1.DryRunAll}
+	}
+	return c.Patch(obj.GetName(), types.ApplyPatchType, data, options)
+}
+
+// ApplyObject server-side applies obj as FieldManager, creating it when missing. The fields the
+// CLI set before with create and update calls are taken over, the conflicts with other managers
+// fail unless force
+func ApplyObject(c dynamic.ResourceInterface, obj *unstructured.Unstructured, force bool, dryRun bool) (*unstructured.Unstructured, error) {
+	applied, err := applyPatch(c, obj, force, dryRun)
+	if err == nil || force || !errors.IsConflict(err) || len(FieldConflicts(err)) > 0 {
+		return applied, err
+	}
+	return applyPatch(c, obj, true, dryRun)
+}
+
+// FieldConflicts lists the fields of an apply error other managers own
+func FieldConflicts(err error) []string {
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+	var conflicts []string
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metaV1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		if match := conflictManagerPattern.FindStringSubmatch(cause.Message); match != nil && match[1] == FieldManager {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%s %s", cause.Field, cause.Message))
+	}
+	return conflicts
+}
+
+// CheckApplyConflicts dry runs the apply of the existing objects update changes and lists the
+// fields other managers own, so update stops before changing anything
+func CheckApplyConflicts(dynamicClient dynamic.Interface, mapper meta.RESTMapper, objectList []runtime.Object) ([]string, error) {
+	var conflicts []string
+	for _, item := range objectList {
+		obj, err := util.ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		if UpdateKeeps(obj) || obj.GetKind() == "CustomResourceDefinition" {
+			// the CRDs are replaced, see ReplaceCRD
+			continue
+		}
+		c, mapping, err := ResourceClient(dynamicClient, mapper, obj)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				// the kind is served once update applies its definition
+				continue
+			}
+			return nil, err
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			obj.SetNamespace("")
+		}
+		_, err = ApplyObject(c, obj, false, true)
+		if err == nil || !errors.IsConflict(err) {
+			// the apply reports the other errors
+			continue
+		}
+		for _, conflict := range FieldConflicts(err) {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s: %s", obj.GetKind(), obj.GetName(), conflict))
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"os"
+)
+
+// CreateBundle downloads a version and writes its offline bundle to output.
+// An empty output names the bundle funceasy-<version>.tar.gz.
+func CreateBundle(version string, output string, verify release.VerifyOptions) (string, error) {
+	r, found := release.GetVersionRelease(version)
+	if !found {
+		return "", fmt.Errorf("Version Not Found: %s", version)
+	}
+	fileByte, err := release.DownloadManifest(r, verify)
+	if err != nil {
+		return "", err
+	}
+	images, err := ListReleaseImages(fileByte, "")
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		output = fmt.Sprintf("funceasy-%s.tar.gz", r.Name)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	err = release.WriteBundle(f, &release.Bundle{
+		Release:  r,
+		Manifest: fileByte,
+		Images:   images,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(output)
+		return "", err
+	}
+	return output, nil
+}
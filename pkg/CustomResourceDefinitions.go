@@ -0,0 +1,225 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	apiextensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServedCRDVersions returns the versions of the apiextensions.k8s.io API the server serves
+func ServedCRDVersions() (map[string]bool, error) {
+	discoveryClient, err := NewK8sDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	served := make(map[string]bool)
+	for _, group := range groups.Groups {
+		if group.Name != apiextensionsV1.GroupName {
+			continue
+		}
+		for _, version := range group.Versions {
+			served[version.Version] = true
+		}
+	}
+	return served, nil
+}
+
+// AdaptCRDVersions converts the v1beta1 CRDs of the objects to v1
+// when the server no longer serves apiextensions.k8s.io/v1beta1, from Kubernetes 1.22
+func AdaptCRDVersions(objectList []runtime.Object) ([]runtime.Object, error) {
+	t := terminal.NewTerminalPrint()
+	served, err := ServedCRDVersions()
+	if err != nil {
+		return nil, err
+	}
+	if served[v1beta1.SchemeGroupVersion.Version] || !served[apiextensionsV1.SchemeGroupVersion.Version] {
+		return objectList, nil
+	}
+	adapted := make([]runtime.Object, 0, len(objectList))
+	for _, item := range objectList {
+		crd, ok := item.(*v1beta1.CustomResourceDefinition)
+		if !ok {
+			adapted = append(adapted, item)
+			continue
+		}
+		converted, err := util.ConvertCRDToV1(crd)
+		if err != nil {
+			return nil, err
+		}
+		t.PrintWarnOneLine("CRD %s Converted To %s", crd.Name, apiextensionsV1.SchemeGroupVersion)
+		t.LineEnd()
+		adapted = append(adapted, converted)
+	}
+	return adapted, nil
+}
+
+type crdVersion struct {
+	served  bool
+	storage bool
+}
+
+// crdVersions reads the versions of a v1 or v1beta1 CRD, the single spec.version of v1beta1 included
+func crdVersions(crd *unstructured.Unstructured) map[string]crdVersion {
+	versions := make(map[string]crdVersion)
+	items, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, item := range items {
+		v, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := v["name"].(string)
+		served, _ := v["served"].(bool)
+		storage, _ := v["storage"].(bool)
+		versions[name] = crdVersion{served: served, storage: storage}
+	}
+	if len(versions) == 0 {
+		if name, found, _ := unstructured.NestedString(crd.Object, "spec", "version"); found {
+			versions[name] = crdVersion{served: true, storage: true}
+		}
+	}
+	return versions
+}
+
+func crdStorageVersion(versions map[string]crdVersion) string {
+	for name, version := range versions {
+		if version.storage {
+			return name
+		}
+	}
+	return ""
+}
+
+// CRDBreakingChanges lists the changes from the live CRD to the desired one that make
+// stored or served custom resources unreachable
+func CRDBreakingChanges(live *unstructured.Unstructured, desired *unstructured.Unstructured) []string {
+	var changes []string
+	for _, field := range [][]string{{"spec", "group"}, {"spec", "scope"}, {"spec", "names", "kind"}, {"spec", "names", "plural"}} {
+		liveValue, _, _ := unstructured.NestedString(live.Object, field...)
+		desiredValue, _, _ := unstructured.NestedString(desired.Object, field...)
+		if desiredValue != "" && liveValue != desiredValue {
+			changes = append(changes, fmt.Sprintf("%s changes from %s to %s", strings.Join(field, "."), liveValue, desiredValue))
+		}
+	}
+	liveVersions := crdVersions(live)
+	desiredVersions := crdVersions(desired)
+	var names []string
+	for name := range liveVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		desiredVersion, ok := desiredVersions[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("version %s is removed", name))
+		} else if liveVersions[name].served && !desiredVersion.served {
+			changes = append(changes, fmt.Sprintf("version %s is no longer served", name))
+		}
+	}
+	liveStorage := crdStorageVersion(liveVersions)
+	desiredStorage := crdStorageVersion(desiredVersions)
+	if liveStorage != desiredStorage {
+		changes = append(changes, fmt.Sprintf("storage version changes from %s to %s", liveStorage, desiredStorage))
+	}
+	storedVersions, _, _ := unstructured.NestedStringSlice(live.Object, "status", "storedVersions")
+	for _, name := range storedVersions {
+		if _, ok := desiredVersions[name]; !ok {
+			changes = append(changes, fmt.Sprintf("objects stored as %s can no longer be read", name))
+		}
+	}
+	return changes
+}
+
+// ReplaceCRD updates the live CRD to the desired one. An apply keeps the fields the install created
+// unless the CLI applied them, a replace drops the removed schema properties, enum values and columns
+func ReplaceCRD(c dynamic.ResourceInterface, live *unstructured.Unstructured, desired *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, error) {
+	replaced := desired.DeepCopy()
+	replaced.SetResourceVersion(live.GetResourceVersion())
+	options := metaV1.UpdateOptions{
+		FieldManager: FieldManager,
+	}
+	if dryRun {
+		options.DryRun = []string{metaV1.DryRunAll}
+	}
+	return c.Update(replaced, options)
+}
+
+// WaitCRDEstablished waits until the CRD serves its custom resources
+func WaitCRDEstablished(c dynamic.ResourceInterface, name string, timeout time.Duration) error {
+	t := terminal.NewTerminalPrint()
+	done := make(chan bool)
+	t.PrintLoadingOneLine(done, "Waiting CRD %s Established", name)
+	deadline := time.Now().Add(timeout)
+	for {
+		crd, err := c.Get(name, metaV1.GetOptions{})
+		if err != nil {
+			done <- true
+			return err
+		}
+		conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+		for _, item := range conditions {
+			condition, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if condition["type"] == "Established" && condition["status"] == "True" {
+				done <- true
+				t.PrintSuccessOneLine("CRD: %s Established", name)
+				t.LineEnd()
+				return nil
+			}
+			if condition["type"] == "NamesAccepted" && condition["status"] == "False" {
+				done <- true
+				return fmt.Errorf("CRD %s Names Not Accepted: %v", name, condition["message"])
+			}
+		}
+		if time.Now().After(deadline) {
+			done <- true
+			return fmt.Errorf("Timeout Waiting CRD %s Established", name)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// CheckCRDUpgrades compares the manifest CRDs with the live ones and lists the breaking changes an update would make
+func CheckCRDUpgrades(dynamicClient dynamic.Interface, mapper meta.RESTMapper, objectList []runtime.Object) ([]string, error) {
+	var changes []string
+	for _, item := range objectList {
+		if util.GetObjectReference(item).Kind != "CustomResourceDefinition" {
+			continue
+		}
+		desired, err := util.ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		c, _, err := ResourceClient(dynamicClient, mapper, desired)
+		if err != nil {
+			return nil, err
+		}
+		live, err := c.Get(desired.GetName(), metaV1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, change := range CRDBreakingChanges(live, desired) {
+			changes = append(changes, fmt.Sprintf("CRD %s: %s", desired.GetName(), change))
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,257 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// serverPopulatedMetadata are the metadata fields the API server sets on every object
+var serverPopulatedMetadata = []string{
+	"uid",
+	"resourceVersion",
+	"generation",
+	"creationTimestamp",
+	"selfLink",
+	"managedFields",
+}
+
+// serverPopulatedAnnotations are the annotations controllers and kubectl set on the objects
+var serverPopulatedAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+}
+
+// objectDiff pairs a live object with its target, a nil live for an object to create
+type objectDiff struct {
+	live   *unstructured.Unstructured
+	target *unstructured.Unstructured
+}
+
+// normalizeForDiff drops the status and the fields the server populates
+func normalizeForDiff(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+	normalized := obj.DeepCopy()
+	delete(normalized.Object, "status")
+	for _, field := range serverPopulatedMetadata {
+		unstructured.RemoveNestedField(normalized.Object, "metadata", field)
+	}
+	annotations := normalized.GetAnnotations()
+	for _, key := range serverPopulatedAnnotations {
+		delete(annotations, key)
+	}
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(normalized.Object, "metadata", "annotations")
+	} else {
+		normalized.SetAnnotations(annotations)
+	}
+	return normalized
+}
+
+// redactForDiff replaces the Secret values with a digest, a changed value still shows as a change
+func redactForDiff(obj *unstructured.Unstructured) {
+	if obj == nil || obj.GetKind() != "Secret" {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		data, found, err := unstructured.NestedMap(obj.Object, field)
+		if err != nil || !found {
+			continue
+		}
+		for key, value := range data {
+			digest := sha256.Sum256([]byte(fmt.Sprint(value)))
+			data[key] = fmt.Sprintf("%s sha256:%x", util.RedactedValue, digest[:6])
+		}
+		_ = unstructured.SetNestedMap(obj.Object, data, field)
+	}
+}
+
+func diffName(prefix string, obj *unstructured.Unstructured) string {
+	if obj == nil {
+		return "/dev/null"
+	}
+	parts := []string{prefix, obj.GetKind()}
+	if obj.GetNamespace() != "" {
+		parts = append(parts, obj.GetNamespace())
+	}
+	return strings.Join(append(parts, obj.GetName()), "/")
+}
+
+func diffText(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	data, err := yaml.Marshal(obj.Object)
+	return string(data), err
+}
+
+// DiffFuncEasyResources compares the live objects of the release with the objects an update to the
+// manifest leaves, with the server defaults of a dry run applied, and prints the diffs. It returns
+// whether the cluster differs from the target
+func DiffFuncEasyResources(fileByte []byte, options *ReleaseOptions) (bool, error) {
+	t := terminal.NewTerminalPrint()
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return false, err
+	}
+	previous, err := GetLatestReleaseRecord(clientSet, options.ReleaseName)
+	if err != nil {
+		return false, err
+	}
+	if previous == nil {
+		t.PrintWarnOneLine("Release %s Not Installed, Every Object Is New", options.ReleaseName)
+		t.LineEnd()
+	}
+	inheritReleaseOptions(options, previous)
+	objectList, err := PrepareFuncEasyResources(fileByte, options)
+	if err != nil {
+		return false, err
+	}
+	objectList, err = AdaptCRDVersions(objectList)
+	if err != nil {
+		return false, err
+	}
+	err = setDatabasePassword(clientSet, objectList, options)
+	if err != nil {
+		return false, err
+	}
+	dynamicClient, mapper, err := NewK8sDynamicClient()
+	if err != nil {
+		return false, err
+	}
+	namespaceExists := true
+	_, err = clientSet.CoreV1().Namespaces().Get(KubeConfig.Namespace, metaV1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+		namespaceExists = false
+	}
+	var diffs []objectDiff
+	targets := make(map[util.ObjectReference]bool)
+	for _, item := range objectList {
+		desired, err := util.ToUnstructured(item)
+		if err != nil {
+			return false, err
+		}
+		c, mapping, err := ResourceClient(dynamicClient, mapper, desired)
+		if err != nil {
+			if !meta.IsNoMatchError(err) {
+				return false, err
+			}
+			// the kind is served once the update creates its definition
+			targets[util.GetObjectReference(desired)] = true
+			diffs = append(diffs, objectDiff{target: desired})
+			continue
+		}
+		namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		if !namespaced {
+			desired.SetNamespace("")
+		}
+		// the records reference the cluster scoped objects without a namespace
+		ref := util.GetObjectReference(desired)
+		targets[ref] = true
+		var live *unstructured.Unstructured
+		if !namespaced || namespaceExists {
+			live, err = c.Get(desired.GetName(), metaV1.GetOptions{})
+			if err != nil {
+				if !errors.IsNotFound(err) {
+					return false, err
+				}
+				live = nil
+			}
+		}
+		target := desired
+		switch {
+		case live == nil && namespaced && !namespaceExists:
+		case live != nil && UpdateKeeps(desired):
+			target = live
+		case live != nil && desired.GetKind() == "CustomResourceDefinition":
+			target, err = ReplaceCRD(c, live, desired, true)
+		default:
+			target, err = ApplyObject(c, desired, false, true)
+			if err != nil && errors.IsConflict(err) {
+				for _, conflict := range FieldConflicts(err) {
+					t.PrintWarnOneLine("Field Conflict %s %s: %s, update needs --force-conflicts", ref.Kind, ref.Name, conflict)
+					t.LineEnd()
+				}
+				target, err = ApplyObject(c, desired, true, true)
+			}
+		}
+		if err != nil {
+			t.PrintWarnOneLine("Server Dry Run %s %s Failed, Comparing The Manifest Object: %s", ref.Kind, ref.Name, err)
+			t.LineEnd()
+			target = desired
+		}
+		diffs = append(diffs, objectDiff{live: live, target: target})
+	}
+	different := false
+	for _, diff := range diffs {
+		live := normalizeForDiff(diff.live)
+		target := normalizeForDiff(diff.target)
+		if !options.ShowSecrets {
+			redactForDiff(live)
+			redactForDiff(target)
+		}
+		liveText, err := diffText(live)
+		if err != nil {
+			return false, err
+		}
+		targetText, err := diffText(target)
+		if err != nil {
+			return false, err
+		}
+		lines := util.UnifiedDiff(diffName("live", live), diffName("target", target), liveText, targetText)
+		if len(lines) > 0 {
+			different = true
+			printDiff(lines)
+		}
+	}
+	if previous != nil {
+		for _, ref := range previous.Objects {
+			if !targets[ref] {
+				t.PrintWarnOneLine("%s %s Not In The Target, Update Keeps It", ref.Kind, ref.Name)
+				t.LineEnd()
+			}
+		}
+	}
+	if !different {
+		t.PrintSuccessOneLine("No Differences")
+		t.LineEnd()
+	}
+	return different, nil
+}
+
+// printDiff prints a unified diff, colored on a terminal
+func printDiff(lines []string) {
+	header := color.New(color.Bold).SprintFunc()
+	hunk := color.New(color.FgCyan).SprintFunc()
+	removed := color.New(color.FgRed).SprintFunc()
+	added := color.New(color.FgGreen).SprintFunc()
+	for i, line := range lines {
+		switch {
+		case i < 2:
+			fmt.Println(header(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(hunk(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(removed(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(added(line))
+		default:
+			fmt.Println(line)
+		}
+	}
+}
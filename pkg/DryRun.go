@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"os"
+)
+
+const (
+	DryRunNone   string = "none"
+	DryRunClient string = "client"
+	DryRunServer string = "server"
+)
+
+// DryRunFuncEasyResources prints the objects an install would create without changing the cluster.
+// The server mode sends every object through the API server dry-run to surface admission errors.
+func DryRunFuncEasyResources(fileByte []byte, options *ReleaseOptions) error {
+	objectList, err := PrepareFuncEasyResources(fileByte, options)
+	if err != nil {
+		return err
+	}
+	if options.DryRun == DryRunServer {
+		objectList, err = AdaptCRDVersions(objectList)
+		if err != nil {
+			return err
+		}
+	}
+	rendered := make([]*unstructured.Unstructured, 0, len(objectList))
+	for _, item := range objectList {
+		u, err := util.ToUnstructured(item)
+		if err != nil {
+			return err
+		}
+		rendered = append(rendered, u)
+	}
+	switch options.DryRun {
+	case DryRunClient:
+	case DryRunServer:
+		rendered, err = serverDryRun(rendered)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unsupported Dry Run Mode: %s", options.DryRun)
+	}
+	if !options.ShowSecrets {
+		util.RedactSecrets(rendered)
+	}
+	return util.PrintObjects(os.Stdout, rendered, options.Output)
+}
+
+func serverDryRun(objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	t := terminal.NewTerminalPrint()
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, mapper, err := NewK8sDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	namespaceExists := true
+	_, err = clientSet.CoreV1().Namespaces().Get(KubeConfig.Namespace, metaV1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		namespaceExists = false
+		t.PrintWarnOneLine("Namespace %s Not Found, Namespaced Objects Are Only Rendered", KubeConfig.Namespace)
+		t.LineEnd()
+	}
+	var failed []string
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		c, mapping, err := ResourceClient(dynamicClient, mapper, obj)
+		if meta.IsNoMatchError(err) {
+			// custom resources of a CRD the manifest creates
+			t.PrintWarnOneLine("%s %s Only Rendered, Its CRD Is Not Installed Yet", obj.GetKind(), obj.GetName())
+			t.LineEnd()
+			result = append(result, obj)
+			continue
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %s", obj.GetKind(), obj.GetName(), err))
+			result = append(result, obj)
+			continue
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && !namespaceExists {
+			result = append(result, obj)
+			continue
+		}
+		created, err := c.Create(obj, metaV1.CreateOptions{DryRun: []string{metaV1.DryRunAll}})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %s", obj.GetKind(), obj.GetName(), err))
+			result = append(result, obj)
+			continue
+		}
+		unstructured.RemoveNestedField(created.Object, "metadata", "managedFields")
+		result = append(result, created)
+	}
+	if len(failed) > 0 {
+		for _, item := range failed {
+			t.PrintErrorOneLine(item)
+		}
+		return nil, fmt.Errorf("Server Dry Run Failed: %d Objects Rejected", len(failed))
+	}
+	return result, nil
+}
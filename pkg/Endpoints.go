@@ -0,0 +1,198 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Endpoint is where an exposed Service of the release is reached, Note says why there is no URL
+type Endpoint struct {
+	Name string
+	URL  string
+	Note string
+}
+
+func endpointURL(scheme string, address string, port int32) string {
+	if scheme == "http" && port == 80 || scheme == "https" && port == 443 || port == 0 {
+		return scheme + "://" + address
+	}
+	return scheme + "://" + address + ":" + strconv.Itoa(int(port))
+}
+
+// nodeAddress returns the external address of a ready node, the internal one if none has
+func nodeAddress(clientSet *kubernetes.Clientset) (string, error) {
+	nodes, err := clientSet.CoreV1().Nodes().List(metaV1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	internal := ""
+	for _, node := range nodes.Items {
+		ready := false
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == coreV1.NodeReady && condition.Status == coreV1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			continue
+		}
+		for _, address := range node.Status.Addresses {
+			if address.Type == coreV1.NodeExternalIP {
+				return address.Address, nil
+			}
+			if address.Type == coreV1.NodeInternalIP && internal == "" {
+				internal = address.Address
+			}
+		}
+	}
+	if internal == "" {
+		return "", fmt.Errorf("No Ready Node Address Found")
+	}
+	return internal, nil
+}
+
+// ingressURLs maps the Services the Ingresses of the release route to their URL
+func ingressURLs(clientSet *kubernetes.Clientset, releaseName string) (map[string]string, error) {
+	urls := make(map[string]string)
+	ingresses, err := clientSet.NetworkingV1beta1().Ingresses(KubeConfig.Namespace).List(metaV1.ListOptions{
+		LabelSelector: labels.Set{util.InstanceLabel: releaseName}.String(),
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return urls, nil
+		}
+		return nil, err
+	}
+	for _, ingress := range ingresses.Items {
+		tls := make(map[string]bool)
+		for _, item := range ingress.Spec.TLS {
+			for _, host := range item.Hosts {
+				tls[host] = true
+			}
+		}
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil || rule.Host == "" {
+				continue
+			}
+			scheme := "http"
+			if tls[rule.Host] {
+				scheme = "https"
+			}
+			for _, path := range rule.HTTP.Paths {
+				if _, ok := urls[path.Backend.ServiceName]; !ok {
+					urls[path.Backend.ServiceName] = endpointURL(scheme, rule.Host, 0) + path.Path
+				}
+			}
+		}
+	}
+	return urls, nil
+}
+
+// GetEndpoints resolves the URLs of the website, API and gateway of the release from its Ingresses,
+// the LoadBalancer addresses or the node addresses, the host recorded by install winning over them
+func GetEndpoints(releaseName string) ([]Endpoint, error) {
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return nil, err
+	}
+	record, err := GetLatestReleaseRecord(clientSet, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	host := ""
+	if record != nil {
+		host = record.Host
+	}
+	urls, err := ingressURLs(clientSet, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []Endpoint
+	for _, exposed := range util.ExposedServices {
+		name := util.ReleaseObjectName(releaseName, exposed.Name)
+		service, err := clientSet.CoreV1().Services(KubeConfig.Namespace).Get(name, metaV1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		endpoint := Endpoint{Name: name}
+		if url, ok := urls[name]; ok {
+			endpoint.URL = url
+			endpoints = append(endpoints, endpoint)
+			continue
+		}
+		if len(service.Spec.Ports) == 0 {
+			endpoint.Note = "no ports"
+			endpoints = append(endpoints, endpoint)
+			continue
+		}
+		port := service.Spec.Ports[0]
+		switch service.Spec.Type {
+		case coreV1.ServiceTypeLoadBalancer:
+			address := host
+			for _, ingress := range service.Status.LoadBalancer.Ingress {
+				if address == "" && ingress.IP != "" {
+					address = ingress.IP
+				}
+				if address == "" && ingress.Hostname != "" {
+					address = ingress.Hostname
+				}
+			}
+			if address == "" {
+				endpoint.Note = "LoadBalancer address pending"
+			} else {
+				endpoint.URL = endpointURL("http", address, port.Port)
+			}
+		case coreV1.ServiceTypeNodePort:
+			address := host
+			if address == "" {
+				address, err = nodeAddress(clientSet)
+				if err != nil {
+					return nil, err
+				}
+			}
+			endpoint.URL = endpointURL("http", address, port.NodePort)
+		default:
+			endpoint.Note = fmt.Sprintf("not exposed, use kubectl port-forward -n %s svc/%s %d", KubeConfig.Namespace, name, port.Port)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// PrintEndpoints prints the endpoints as a table
+func PrintEndpoints(endpoints []Endpoint) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SERVICE\tURL")
+	for _, endpoint := range endpoints {
+		url := endpoint.URL
+		if url == "" {
+			url = "<" + endpoint.Note + ">"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", endpoint.Name, url)
+	}
+	_ = w.Flush()
+}
+
+// printReleaseEndpoints prints the URLs of the release after an install or update
+func printReleaseEndpoints(releaseName string) {
+	t := terminal.NewTerminalPrint()
+	endpoints, err := GetEndpoints(releaseName)
+	if err != nil {
+		t.PrintWarnOneLine("Resolve Endpoints Failed: %s", err)
+		t.LineEnd()
+		return
+	}
+	PrintEndpoints(endpoints)
+}
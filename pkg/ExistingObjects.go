@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"sort"
+)
+
+// ExistingObject is a manifest object already present in the cluster
+type ExistingObject struct {
+	Reference util.ObjectReference
+	Live      *unstructured.Unstructured
+	Desired   *unstructured.Unstructured
+	// Owned is true when the live object carries the instance label of the release
+	Owned bool
+	// Matches is true when every field the manifest sets has the same live value
+	Matches bool
+	// Shared is true for the cluster wide definitions all releases use
+	Shared bool
+}
+
+// FindExistingObjects looks up every manifest object in the cluster
+func FindExistingObjects(dynamicClient dynamic.Interface, mapper meta.RESTMapper, objectList []runtime.Object, releaseName string) (map[util.ObjectReference]*ExistingObject, error) {
+	existing := make(map[util.ObjectReference]*ExistingObject)
+	for _, item := range objectList {
+		desired, err := util.ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		c, _, err := ResourceClient(dynamicClient, mapper, desired)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				// the kind is not served yet, CheckObjectKinds reports it
+				continue
+			}
+			return nil, err
+		}
+		live, err := c.Get(desired.GetName(), metaV1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		ref := util.GetObjectReference(item)
+		existing[ref] = &ExistingObject{
+			Reference: ref,
+			Live:      live,
+			Desired:   desired,
+			Owned:     live.GetLabels()[util.InstanceLabel] == releaseName,
+			Matches:   util.IsSubset(comparableFields(desired), live.Object),
+			Shared:    ref.Kind == "CustomResourceDefinition",
+		}
+	}
+	return existing, nil
+}
+
+// comparableFields drops the fields of a desired object the cluster is expected to own or change
+func comparableFields(desired *unstructured.Unstructured) map[string]interface{} {
+	content := desired.DeepCopy().Object
+	labels := desired.GetLabels()
+	annotations := desired.GetAnnotations()
+	delete(content, "metadata")
+	if len(labels) > 0 || len(annotations) > 0 {
+		metadata := make(map[string]interface{})
+		if len(labels) > 0 {
+			metadata["labels"] = toInterfaceMap(labels)
+		}
+		if len(annotations) > 0 {
+			metadata["annotations"] = toInterfaceMap(annotations)
+		}
+		content["metadata"] = metadata
+	}
+	if desired.GetKind() == "Secret" && labels["generatedBy"] == "cli" {
+		// the keys are generated on every run
+		delete(content, "data")
+	}
+	return content
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
+
+// ReplaceExistingObject updates a live object of the release to the manifest content
+func ReplaceExistingObject(dynamicClient dynamic.Interface, mapper meta.RESTMapper, existing *ExistingObject) error {
+	desired := existing.Desired.DeepCopy()
+	desired.SetResourceVersion(existing.Live.GetResourceVersion())
+	if desired.GetKind() == "Service" {
+		// the cluster IP can not change once allocated
+		clusterIP, found, _ := unstructured.NestedString(existing.Live.Object, "spec", "clusterIP")
+		if found {
+			_ = unstructured.SetNestedField(desired.Object, clusterIP, "spec", "clusterIP")
+		}
+		keepNodePorts(desired, existing.Live)
+	}
+	if desired.GetKind() == "Secret" && desired.GetLabels()["generatedBy"] == "cli" {
+		// keep the keys the previous run handed out
+		data, found, _ := unstructured.NestedMap(existing.Live.Object, "data")
+		if found {
+			_ = unstructured.SetNestedMap(desired.Object, data, "data")
+		}
+	}
+	c, _, err := ResourceClient(dynamicClient, mapper, desired)
+	if err != nil {
+		return err
+	}
+	_, err = c.Update(desired, metaV1.UpdateOptions{})
+	return err
+}
+
+// keepNodePorts copies the node ports the cluster allocated to the desired ports
+// that leave them unset, matching the ports by name or else by port number
+func keepNodePorts(desired *unstructured.Unstructured, live *unstructured.Unstructured) {
+	desiredPorts, _, _ := unstructured.NestedSlice(desired.Object, "spec", "ports")
+	livePorts, _, _ := unstructured.NestedSlice(live.Object, "spec", "ports")
+	for _, item := range desiredPorts {
+		port, ok := item.(map[string]interface{})
+		if !ok || port["nodePort"] != nil && fmt.Sprint(port["nodePort"]) != "0" {
+			continue
+		}
+		for _, liveItem := range livePorts {
+			livePort, ok := liveItem.(map[string]interface{})
+			if !ok || livePort["nodePort"] == nil {
+				continue
+			}
+			if port["name"] != nil && port["name"] == livePort["name"] ||
+				port["name"] == nil && fmt.Sprint(port["port"]) == fmt.Sprint(livePort["port"]) {
+				port["nodePort"] = livePort["nodePort"]
+				break
+			}
+		}
+	}
+	if len(desiredPorts) > 0 {
+		_ = unstructured.SetNestedSlice(desired.Object, desiredPorts, "spec", "ports")
+	}
+}
+
+// ExistingObjectConflicts lists the existing objects install can neither adopt nor resume
+func ExistingObjectConflicts(existing map[util.ObjectReference]*ExistingObject, resume bool) []string {
+	var conflicts []string
+	for ref, item := range existing {
+		if item.Matches || item.Shared || (item.Owned && resume) {
+			continue
+		}
+		reason := "differs from the manifest"
+		if item.Owned {
+			reason = "left by a previous run, use --resume"
+		} else if owner := item.Live.GetLabels()[util.InstanceLabel]; owner != "" {
+			reason = fmt.Sprintf("owned by release %s", owner)
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%s %s: %s", ref.Kind, ref.Name, reason))
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
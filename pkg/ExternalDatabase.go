@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DatabaseCheckImage runs the Job checking the external database accepts the user
+const DatabaseCheckImage = "mysql:5.7"
+
+// NewDatabaseCheckJob returns the Job running a query on the external database from inside the cluster
+func NewDatabaseCheckJob(options *ReleaseOptions) *batchV1.Job {
+	database := options.Database
+	backoffLimit := int32(1)
+	return &batchV1.Job{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      util.ReleaseObjectName(options.ReleaseName, "funceasy-database-check"),
+			Namespace: KubeConfig.Namespace,
+			Labels: map[string]string{
+				util.InstanceLabel: options.ReleaseName,
+			},
+		},
+		Spec: batchV1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: coreV1.PodTemplateSpec{
+				Spec: coreV1.PodSpec{
+					RestartPolicy:    coreV1.RestartPolicyNever,
+					ImagePullSecrets: jobPullSecrets(options),
+					Containers: []coreV1.Container{
+						{
+							Name:    "check",
+							Image:   util.MirrorImage(DatabaseCheckImage, options.ImageRegistry),
+							Command: []string{"mysql", "--connect-timeout=10", "-h", database.Host, "-P", database.Port, "-u", database.User, "-e", "SELECT 1"},
+							Env: []coreV1.EnvVar{
+								{
+									Name: "MYSQL_PWD",
+									ValueFrom: &coreV1.EnvVarSource{
+										SecretKeyRef: &coreV1.SecretKeySelector{
+											LocalObjectReference: coreV1.LocalObjectReference{Name: database.PasswordSecret},
+											Key:                  database.PasswordKey,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// PrepareExternalDatabase copies the password into the connection Secret and checks
+// the database accepts the connection before the services using it roll out
+func PrepareExternalDatabase(clientSet *kubernetes.Clientset, objectList []runtime.Object, options *ReleaseOptions) error {
+	database := options.Database
+	if database == nil {
+		return nil
+	}
+	t := terminal.NewTerminalPrint()
+	err := setDatabasePassword(clientSet, objectList, options)
+	if err != nil {
+		return err
+	}
+	t.PrintInfoOneLine("Checking External Database: %s:%s", database.Host, database.Port)
+	err = RunJob(clientSet, NewDatabaseCheckJob(options), options.Timeout)
+	if err != nil {
+		return fmt.Errorf("External Database Check Failed: %s", err)
+	}
+	t.PrintSuccessOneLine("External Database: %s:%s Reachable", database.Host, database.Port)
+	t.LineEnd()
+	return nil
+}
+
+// setDatabasePassword copies the password of the password Secret into the connection Secret
+func setDatabasePassword(clientSet *kubernetes.Clientset, objectList []runtime.Object, options *ReleaseOptions) error {
+	database := options.Database
+	if database == nil {
+		return nil
+	}
+	passwordSecret, err := clientSet.CoreV1().Secrets(KubeConfig.Namespace).Get(database.PasswordSecret, metaV1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("Read Database Password Failed: %s", err)
+	}
+	password, ok := passwordSecret.Data[database.PasswordKey]
+	if !ok {
+		return fmt.Errorf("Database Password Secret %s Has No Key %s", database.PasswordSecret, database.PasswordKey)
+	}
+	secretName := util.ReleaseObjectName(options.ReleaseName, util.DatabaseSecretName)
+	for _, item := range objectList {
+		if secret, ok := item.(*coreV1.Secret); ok && secret.Name == secretName {
+			secret.Data["password"] = password
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/pflag"
+	"io/ioutil"
+	"k8s.io/client-go/util/homedir"
+	"path/filepath"
+	"sort"
+)
+
+// InstallFlags are the flags choosing the manifest and shaping the objects of an install,
+// preflight takes the same ones so it checks the install the flags describe
+func InstallFlags() *pflag.FlagSet {
+	var mountPath string
+	if home := homedir.HomeDir(); home != "" {
+		mountPath = filepath.Join(home, "mnt", "funceasy-data")
+	} else {
+		mountPath = "/mnt/funceasy-data"
+	}
+	flags := pflag.NewFlagSet("install", pflag.ExitOnError)
+	flags.StringP("file", "f", "", "the yaml file path to install")
+	flags.AddFlagSet(release.VerifyFlags())
+	flags.String("bundle", "", "the offline bundle to install, see bundle create")
+	flags.StringP("local", "l", mountPath, "the local mount path")
+	flags.StringP("storage-class", "s", "", "the PVC StorageClass name")
+	flags.String("node", "", "create local volumes on this node, matched on its kubernetes.io/hostname label, instead of hostPath volumes on this machine")
+	flags.String("reclaim-policy", string(DefaultReclaimPolicy), "the reclaim policy of the local volumes: Retain, Delete or Recycle")
+	flags.StringArray("values", []string{}, "a values file patching the manifest objects, can be repeated")
+	flags.StringArray("set", []string{}, "override a manifest field: kind.name.path=value, can be repeated")
+	flags.String("image-registry", "", "rewrite the images to this mirror registry")
+	flags.StringArray("image-pull-secret", []string{}, "a Secret to pull the images with, can be repeated")
+	flags.String("expose", "", "expose the website, API and gateway: nodeport, loadbalancer or ingress")
+	flags.String("host", "", "the host the Ingress routes, its api. and gateway. subdomains included, or the address the endpoints use")
+	flags.String("profile", "", "adjust the replicas, resources, key size and exposure: dev, minimal, production or a profile of the config file")
+	flags.String("external-database", "", "use this MySQL host:port instead of the bundled funceasy-mysql")
+	flags.String("db-user", "", "the user of the external database")
+	flags.String("db-password-from-secret", "", "the Secret holding the external database password: name[:key], the key defaults to password")
+	return flags
+}
+
+// LoadInstallValues reads the values files of the values flag and sets the flags of their install section
+// the command line left unset, it returns the values and the version of the section. A flag the command
+// does not take is an error when strict, update and diff warn instead and keep the installed setting
+func LoadInstallValues(flags *pflag.FlagSet, strict bool) (util.Values, string, error) {
+	t := terminal.NewTerminalPrint()
+	valuesFiles, err := flags.GetStringArray("values")
+	if err != nil {
+		return nil, "", err
+	}
+	values, err := util.LoadValuesFiles(valuesFiles)
+	if err != nil {
+		return nil, "", err
+	}
+	installFlags, err := values.InstallFlags()
+	if err != nil {
+		return nil, "", err
+	}
+	version := installFlags[WizardVersionKey]
+	delete(installFlags, WizardVersionKey)
+	var names []string
+	for name := range installFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flags.Lookup(name) == nil && !strict {
+			t.PrintWarnOneLine("Values %s.%s Ignored, the installed setting is kept", util.InstallValuesKey, name)
+			t.LineEnd()
+			delete(installFlags, name)
+		}
+	}
+	err = util.SetUnchangedFlags(flags, installFlags)
+	if err != nil {
+		return nil, "", fmt.Errorf("Values %s: %s", util.InstallValuesKey, err)
+	}
+	return values, version, nil
+}
+
+// GetInstallOptions reads the flags InstallFlags adds into the options of an install of releaseName
+func GetInstallOptions(flags *pflag.FlagSet, releaseName string, values util.Values) (*ReleaseOptions, error) {
+	local, err := flags.GetString("local")
+	if err != nil {
+		return nil, err
+	}
+	sc, err := flags.GetString("storage-class")
+	if err != nil {
+		return nil, err
+	}
+	node, err := flags.GetString("node")
+	if err != nil {
+		return nil, err
+	}
+	reclaimPolicy, err := flags.GetString("reclaim-policy")
+	if err != nil {
+		return nil, err
+	}
+	sets, err := flags.GetStringArray("set")
+	if err != nil {
+		return nil, err
+	}
+	imageRegistry, err := flags.GetString("image-registry")
+	if err != nil {
+		return nil, err
+	}
+	imagePullSecrets, err := flags.GetStringArray("image-pull-secret")
+	if err != nil {
+		return nil, err
+	}
+	expose, err := flags.GetString("expose")
+	if err != nil {
+		return nil, err
+	}
+	host, err := flags.GetString("host")
+	if err != nil {
+		return nil, err
+	}
+	profile, err := flags.GetString("profile")
+	if err != nil {
+		return nil, err
+	}
+	externalDatabase, err := flags.GetString("external-database")
+	if err != nil {
+		return nil, err
+	}
+	dbUser, err := flags.GetString("db-user")
+	if err != nil {
+		return nil, err
+	}
+	dbPasswordFrom, err := flags.GetString("db-password-from-secret")
+	if err != nil {
+		return nil, err
+	}
+	var database *util.ExternalDatabase
+	if externalDatabase != "" {
+		database, err = util.ParseExternalDatabase(externalDatabase, dbUser, dbPasswordFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+	options := &ReleaseOptions{
+		ReleaseName:      releaseName,
+		Flags:            util.ChangedFlags(flags),
+		Values:           values,
+		Sets:             sets,
+		ImageRegistry:    imageRegistry,
+		ImagePullSecrets: imagePullSecrets,
+		Expose:           expose,
+		Host:             host,
+		Database:         database,
+		Profile:          profile,
+	}
+	if sc != "" && !flags.Changed("local") {
+		// --local has a default, a StorageClass alone replaces it
+		local = ""
+	}
+	if local != "" && sc == "" {
+		options.PVType = "Local"
+		options.PathOrClass = local
+		options.Node = node
+		options.ReclaimPolicy = reclaimPolicy
+	} else if local == "" && sc != "" {
+		options.PVType = "StorageClass"
+		options.PathOrClass = sc
+	} else {
+		return nil, fmt.Errorf("Only one type: Local or StorageClass")
+	}
+	return options, nil
+}
+
+// LoadInstallManifest reads the manifest of the file or bundle flag, or downloads the version of args
+func LoadInstallManifest(flags *pflag.FlagSet, args []string) ([]byte, error) {
+	t := terminal.NewTerminalPrint()
+	filePath, err := flags.GetString("file")
+	if err != nil {
+		return nil, err
+	}
+	verify, err := release.GetVerifyOptions(flags)
+	if err != nil {
+		return nil, err
+	}
+	bundlePath, err := flags.GetString("bundle")
+	if err != nil {
+		return nil, err
+	}
+	if bundlePath != "" && filePath == "" && len(args) == 0 {
+		b, err := release.ReadBundle(bundlePath)
+		if err != nil {
+			return nil, err
+		}
+		t.PrintInfoOneLine("Bundle Version: %s", b.Release.Name)
+		t.LineEnd()
+		return b.Manifest, nil
+	} else if filePath != "" && bundlePath == "" && len(args) == 0 {
+		fileByte, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("Read Yaml File Error: %s", err)
+		}
+		return fileByte, nil
+	} else if filePath == "" && bundlePath == "" && len(args) == 1 {
+		r, found := release.GetVersionRelease(args[0])
+		if !found {
+			return nil, fmt.Errorf("Version Not Found: %s", args[0])
+		}
+		return release.DownloadManifest(r, verify)
+	}
+	return nil, fmt.Errorf("Use arg <version> or flags [--file|--bundle] ")
+}
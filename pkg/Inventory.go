@@ -0,0 +1,236 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CLIVersion is set at build time with -ldflags "-X github.com/funceasy/funceasy-cli/pkg.CLIVersion=..."
+var CLIVersion = "dev"
+
+const ReleaseRecordType coreV1.SecretType = "funceasy.io/release.v1"
+
+const ReleaseRecordKey string = "release"
+
+// DefaultHistoryMax is the number of revisions kept per release, 0 keeps them all
+const DefaultHistoryMax = 10
+
+// ReleaseOptions are the user choices an install or update runs with
+type ReleaseOptions struct {
+	ReleaseName string
+	PVType      string
+	PathOrClass string
+	// Node holds the local volumes, they are hostPath volumes on the machine running the CLI without it
+	Node          string
+	ReclaimPolicy string
+	Flags         map[string]string
+	DryRun        string
+	Output        string
+	ShowSecrets   bool
+	Resume        bool
+	// NoRollback keeps the changes of a failed install for debugging
+	NoRollback       bool
+	Values           util.Values
+	Sets             []string
+	ResetValues      bool
+	ImageRegistry    string
+	ImagePullSecrets []string
+	Wait             bool
+	Timeout          time.Duration
+	// Expose is how the website, API and gateway are reached, see util.ExposeObjects
+	Expose string
+	Host   string
+	// Database replaces the bundled funceasy-mysql when set
+	Database *util.ExternalDatabase
+	// Profile is a built-in profile or one of the config file, see GetProfile
+	Profile string
+	// AllowCRDBreakingChanges lets update apply CRD changes that strand stored or served custom resources
+	AllowCRDBreakingChanges bool
+	// ForceConflicts lets update take over the fields other managers own, see ApplyObject
+	ForceConflicts bool
+	// HistoryMax is the number of revisions kept after a save, see PruneReleaseRecords
+	HistoryMax int
+}
+
+// ReleaseRecord is the inventory of one install or update revision, stored as a Secret
+type ReleaseRecord struct {
+	Name             string                 `json:"name"`
+	Namespace        string                 `json:"namespace"`
+	Revision         int                    `json:"revision"`
+	Action           string                 `json:"action"`
+	Version          string                 `json:"version"`
+	ManifestDigest   string                 `json:"manifestDigest"`
+	CLIVersion       string                 `json:"cliVersion"`
+	PVType           string                 `json:"pvType"`
+	PathOrClass      string                 `json:"pathOrClass"`
+	Node             string                 `json:"node,omitempty"`
+	ReclaimPolicy    string                 `json:"reclaimPolicy,omitempty"`
+	Flags            map[string]string      `json:"flags"`
+	Values           util.Values            `json:"values,omitempty"`
+	Sets             []string               `json:"sets,omitempty"`
+	ImageRegistry    string                 `json:"imageRegistry,omitempty"`
+	ImagePullSecrets []string               `json:"imagePullSecrets,omitempty"`
+	Expose           string                 `json:"expose,omitempty"`
+	Host             string                 `json:"host,omitempty"`
+	Database         *util.ExternalDatabase `json:"database,omitempty"`
+	Profile          string                 `json:"profile,omitempty"`
+	Objects          []util.ObjectReference `json:"objects"`
+	Timestamp        time.Time              `json:"timestamp"`
+}
+
+func NewReleaseRecord(action string, fileByte []byte, objectList []runtime.Object, options *ReleaseOptions) *ReleaseRecord {
+	digest := sha256.Sum256(fileByte)
+	return &ReleaseRecord{
+		Name:             options.ReleaseName,
+		Namespace:        KubeConfig.Namespace,
+		Action:           action,
+		Version:          GetManifestVersion(objectList, options.ReleaseName),
+		ManifestDigest:   "sha256:" + hex.EncodeToString(digest[:]),
+		CLIVersion:       CLIVersion,
+		PVType:           options.PVType,
+		PathOrClass:      options.PathOrClass,
+		Node:             options.Node,
+		ReclaimPolicy:    options.ReclaimPolicy,
+		Flags:            options.Flags,
+		Values:           options.Values,
+		Sets:             options.Sets,
+		ImageRegistry:    options.ImageRegistry,
+		ImagePullSecrets: options.ImagePullSecrets,
+		Expose:           options.Expose,
+		Host:             options.Host,
+		Database:         options.Database,
+		Profile:          options.Profile,
+		Timestamp:        time.Now().UTC(),
+	}
+}
+
+// GetManifestVersion reads the FuncEasy version from the funceasy-config ConfigMap of the manifest
+func GetManifestVersion(objectList []runtime.Object, releaseName string) string {
+	for _, item := range objectList {
+		if cm, ok := item.(*coreV1.ConfigMap); ok && cm.Name == util.ReleaseObjectName(releaseName, "funceasy-config") {
+			return cm.Data["version"]
+		}
+	}
+	return ""
+}
+
+func releaseRecordSelector(releaseName string) string {
+	return labels.Set(map[string]string{
+		"owner": "funceasy-cli",
+		"name":  releaseName,
+	}).String()
+}
+
+func releaseRecordName(releaseName string, revision int) string {
+	return fmt.Sprintf("funceasy-release.%s.v%d", releaseName, revision)
+}
+
+// SaveReleaseRecord stores the record as the next revision of the release
+func SaveReleaseRecord(clientSet *kubernetes.Clientset, record *ReleaseRecord) error {
+	records, err := ListReleaseRecords(clientSet, record.Name)
+	if err != nil {
+		return err
+	}
+	record.Revision = 1
+	if len(records) > 0 {
+		record.Revision = records[len(records)-1].Revision + 1
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	secret := &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      releaseRecordName(record.Name, record.Revision),
+			Namespace: record.Namespace,
+			Labels: map[string]string{
+				"owner":   "funceasy-cli",
+				"name":    record.Name,
+				"version": strconv.Itoa(record.Revision),
+			},
+		},
+		Type: ReleaseRecordType,
+		Data: map[string][]byte{
+			ReleaseRecordKey: data,
+		},
+	}
+	_, err = clientSet.CoreV1().Secrets(record.Namespace).Create(secret)
+	return err
+}
+
+// PruneReleaseRecords deletes the oldest revisions of the release beyond historyMax, 0 keeps them all
+func PruneReleaseRecords(clientSet *kubernetes.Clientset, releaseName string, historyMax int) error {
+	if historyMax <= 0 {
+		return nil
+	}
+	records, err := ListReleaseRecords(clientSet, releaseName)
+	if err != nil {
+		return err
+	}
+	for len(records) > historyMax {
+		err = clientSet.CoreV1().Secrets(KubeConfig.Namespace).Delete(releaseRecordName(releaseName, records[0].Revision), &metaV1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		records = records[1:]
+	}
+	return nil
+}
+
+// ListReleaseRecords returns the revisions of the release, oldest first
+func ListReleaseRecords(clientSet *kubernetes.Clientset, releaseName string) ([]*ReleaseRecord, error) {
+	secrets, err := clientSet.CoreV1().Secrets(KubeConfig.Namespace).List(metaV1.ListOptions{
+		LabelSelector: releaseRecordSelector(releaseName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var records []*ReleaseRecord
+	for _, secret := range secrets.Items {
+		if secret.Type != ReleaseRecordType {
+			continue
+		}
+		record := &ReleaseRecord{}
+		err := json.Unmarshal(secret.Data[ReleaseRecordKey], record)
+		if err != nil {
+			return nil, fmt.Errorf("Decode Release Record %s Failed: %s", secret.Name, err)
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Revision < records[j].Revision
+	})
+	return records, nil
+}
+
+// GetLatestReleaseRecord returns the last revision of the release or nil if none is recorded
+func GetLatestReleaseRecord(clientSet *kubernetes.Clientset, releaseName string) (*ReleaseRecord, error) {
+	records, err := ListReleaseRecords(clientSet, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[len(records)-1], nil
+}
+
+func GetReleaseHistory(releaseName string) ([]*ReleaseRecord, error) {
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return ListReleaseRecords(clientSet, releaseName)
+}
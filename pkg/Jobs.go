@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"fmt"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"time"
+)
+
+// RunJob runs a one-shot Job to completion and removes it, a failed Job is kept for its logs
+// and replaced by the next run
+func RunJob(clientSet *kubernetes.Clientset, job *batchV1.Job, timeout time.Duration) error {
+	jobClient := clientSet.BatchV1().Jobs(job.Namespace)
+	background := metaV1.DeletePropagationBackground
+	deleteOptions := &metaV1.DeleteOptions{PropagationPolicy: &background}
+	err := jobClient.Delete(job.Name, deleteOptions)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	_, err = jobClient.Create(job)
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = DefaultRolloutTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		current, err := jobClient.Get(job.Name, metaV1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current.Status.Succeeded > 0 {
+			break
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == batchV1.JobFailed && condition.Status == coreV1.ConditionTrue {
+				return fmt.Errorf("Job %s Failed: %s, see its logs", job.Name, condition.Message)
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Job %s Timed Out After %s", job.Name, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+	err = jobClient.Delete(job.Name, deleteOptions)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// jobPullSecrets returns the pull secrets of the release for the Jobs the CLI runs
+func jobPullSecrets(options *ReleaseOptions) []coreV1.LocalObjectReference {
+	var references []coreV1.LocalObjectReference
+	for _, secret := range options.ImagePullSecrets {
+		references = append(references, coreV1.LocalObjectReference{Name: secret})
+	}
+	return references
+}
@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+const (
+	JournalCreated  = "Created"
+	JournalModified = "Modified"
+)
+
+// JournalEntry is one change a run made to the cluster and the way to undo it
+type JournalEntry struct {
+	Reference util.ObjectReference
+	Change    string
+	undo      func() error
+}
+
+// Journal records the changes of an install so a failure can undo exactly them,
+// the objects the install found in the cluster and kept as they were are never recorded
+type Journal struct {
+	mutex   sync.Mutex
+	entries []*JournalEntry
+	// closed once rolled back, changes after it would escape the rollback
+	closed bool
+}
+
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// Apply runs the change and records it, the rollback waits for a change in progress
+func (j *Journal) Apply(ref util.ObjectReference, change string, apply func() error, undo func() error) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.closed {
+		return fmt.Errorf("Install Rolled Back")
+	}
+	err := apply()
+	if err != nil {
+		return err
+	}
+	j.entries = append(j.entries, &JournalEntry{Reference: ref, Change: change, undo: undo})
+	return nil
+}
+
+// Add records a change already made
+func (j *Journal) Add(ref util.ObjectReference, change string, undo func() error) error {
+	return j.Apply(ref, change, func() error { return nil }, undo)
+}
+
+// Rollback undoes the recorded changes, the last one first. A failing undo is reported
+// and the rollback goes on with the other changes, the failed count is returned
+func (j *Journal) Rollback() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.closed {
+		return 0
+	}
+	j.closed = true
+	t := terminal.NewTerminalPrint()
+	t.PrintWarnOneLine("Start RollBack: %d Changes", len(j.entries))
+	t.LineEnd()
+	failed := 0
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		entry := j.entries[i]
+		ref := entry.Reference
+		err := entry.undo()
+		if err != nil {
+			failed++
+			t.PrintErrorOneLine(fmt.Sprintf("RollBack %s %s: %s Failed: ", entry.Change, ref.Kind, ref.Name), err)
+			continue
+		}
+		t.PrintSuccessOneLine("RollBack %s %s: %s", entry.Change, ref.Kind, ref.Name)
+		t.LineEnd()
+	}
+	if failed > 0 {
+		t.PrintWarnOneLine("RollBack Incomplete: %d Of %d Changes Failed, undo them by hand", failed, len(j.entries))
+	} else {
+		t.PrintSuccessOneLine("RollBack Complete: %d Changes", len(j.entries))
+	}
+	t.LineEnd()
+	return failed
+}
+
+// Report prints the recorded changes, for the runs keeping them
+func (j *Journal) Report() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.closed = true
+	t := terminal.NewTerminalPrint()
+	t.PrintWarnOneLine("RollBack Skipped: %d Changes Kept", len(j.entries))
+	t.LineEnd()
+	for _, entry := range j.entries {
+		fmt.Printf("  %s %s: %s\n", entry.Change, entry.Reference.Kind, entry.Reference.Name)
+	}
+}
+
+// Fail ends the run with err, rolling the changes back unless noRollback
+func (j *Journal) Fail(err error, noRollback bool) error {
+	t := terminal.NewTerminalPrint()
+	t.PrintErrorOneLine(err)
+	if noRollback {
+		j.Report()
+	} else {
+		j.Rollback()
+	}
+	return err
+}
+
+// FailOnInterrupt ends the run like Fail on Ctrl-C, a second Ctrl-C aborts the rollback.
+// The returned func stops watching once the run is over
+func (j *Journal) FailOnInterrupt(noRollback bool) func() {
+	interrupts := make(chan os.Signal, 1)
+	done := make(chan bool)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-interrupts:
+			signal.Stop(interrupts)
+			_ = j.Fail(fmt.Errorf("Install Interrupted"), noRollback)
+			os.Exit(130)
+		}
+	}()
+	return func() {
+		signal.Stop(interrupts)
+		close(done)
+	}
+}
+
+// RestoreObject puts a live object the run modified back to the content it had before
+func RestoreObject(dynamicClient dynamic.Interface, mapper meta.RESTMapper, previous *unstructured.Unstructured) error {
+	c, _, err := ResourceClient(dynamicClient, mapper, previous)
+	if err != nil {
+		return err
+	}
+	current, err := c.Get(previous.GetName(), metaV1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	restored := previous.DeepCopy()
+	restored.SetResourceVersion(current.GetResourceVersion())
+	_, err = c.Update(restored, metaV1.UpdateOptions{})
+	return err
+}
@@ -1,487 +1,624 @@
 package pkg
 
 import (
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/funceasy/funceasy-cli/pkg/util"
 	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
-	"github.com/sirupsen/logrus"
 	appsV1 "k8s.io/api/apps/v1"
 	coreV1 "k8s.io/api/core/v1"
-	rbacV1 "k8s.io/api/rbac/v1"
+	apiextensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
-	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 	"os"
 	"path"
-	"path/filepath"
-	"reflect"
 	"time"
 )
 
-const NAMESPACE string = "funceasy"
+const DefaultNamespace string = "funceasy"
 
-func NewK8sClientSet() (kubernetesClient *kubernetes.Clientset, apiExtensionsClient *apiextensionsclient.Clientset) {
-	var kubeConfig string
-	if home := homedir.HomeDir(); home != "" {
-		kubeConfig = filepath.Join(home, ".kube", "config")
-	} else {
-		kubeConfig = ""
+// ParseFuncEasyResources parses the manifest, applies the values and moves the objects into the release
+func ParseFuncEasyResources(fileByte []byte, options *ReleaseOptions) ([]runtime.Object, error) {
+	err := v1beta1.AddToScheme(scheme.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	err = apiextensionsV1.AddToScheme(scheme.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	objectList, err := util.ParseK8sYaml(fileByte)
+	if err != nil {
+		return nil, err
 	}
-	cfg, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	profile, err := GetProfile(options.Profile)
 	if err != nil {
-		logrus.Fatal(err)
+		return nil, err
+	}
+	// the values come after the profile so they win over it
+	objectList = util.ApplyProfile(objectList, profile)
+	objectList, err = util.ApplyValues(objectList, options.Values, options.Sets)
+	if err != nil {
+		return nil, err
+	}
+	objectList = util.SetExternalDatabase(objectList, options.Database)
+	util.SetObjectsImageRegistry(objectList, options.ImageRegistry)
+	util.SetObjectsImagePullSecrets(objectList, options.ImagePullSecrets)
+	expose := options.Expose
+	if expose == "" && profile != nil {
+		expose = profile.Expose
 	}
-	clientSet, err := kubernetes.NewForConfig(cfg)
+	objectList, err = util.ExposeObjects(objectList, expose, options.Host)
 	if err != nil {
-		logrus.Fatal(err)
+		return nil, err
 	}
-	apiExtensionsClientSet, err := apiextensionsclient.NewForConfig(cfg)
+	util.SetObjectsRelease(objectList, options.ReleaseName)
+	util.SetObjectsNamespace(objectList, KubeConfig.Namespace)
+	objectList, err = util.SortObjects(objectList)
 	if err != nil {
-		logrus.Fatal(err)
+		return nil, err
 	}
-	return clientSet, apiExtensionsClientSet
+	return util.OrderDeployments(objectList)
 }
 
-func DeployFuncEasyResources(fileByte []byte, PVType string, pathOrClass string) error {
-	err := v1beta1.AddToScheme(scheme.Scheme)
+// ListReleaseImages returns the images the manifest references and the images of the Jobs an install
+// may run, moved to registry if set
+func ListReleaseImages(fileByte []byte, registry string) ([]string, error) {
+	objectList, err := ParseFuncEasyResources(fileByte, &ReleaseOptions{
+		ReleaseName:   util.DefaultReleaseName,
+		ImageRegistry: registry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the connectivity check of --external-database runs a Job
+	images := util.MergeImages(util.ListImages(objectList), util.MirrorImage(DatabaseCheckImage, registry))
+	for _, item := range objectList {
+		if _, ok := item.(*coreV1.PersistentVolumeClaim); ok {
+			// the local volumes of --node are prepared by a Job
+			images = util.MergeImages(images, util.MirrorImage(PrepareVolumesImage, registry))
+			break
+		}
+	}
+	return images, nil
+}
+
+// PrepareFuncEasyResources parses the manifest and returns the exact objects an install creates,
+// with the release names, the namespace, the generated keys and the storage settings applied
+func PrepareFuncEasyResources(fileByte []byte, options *ReleaseOptions) ([]runtime.Object, error) {
+	objectList, err := ParseFuncEasyResources(fileByte, options)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateReclaimPolicy(options)
+	if err != nil {
+		return nil, err
+	}
+	keyBits := util.DefaultKeyBits
+	profile, err := GetProfile(options.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil && profile.KeyBits > 0 {
+		keyBits = profile.KeyBits
+	}
+	prepared := make([]runtime.Object, 0, len(objectList))
+	for _, item := range objectList {
+		switch item.(type) {
+		case *coreV1.Secret:
+			err := GenerateSecretData(item.(*coreV1.Secret), keyBits)
+			if err != nil {
+				return nil, err
+			}
+		case *coreV1.PersistentVolumeClaim:
+			pvc := item.(*coreV1.PersistentVolumeClaim)
+			if options.PVType == "Local" {
+				prepared = append(prepared, NewLocalPV(pvc, options))
+			}
+			if options.PVType == "StorageClass" {
+				pathOrClass := options.PathOrClass
+				pvc.Spec.StorageClassName = &pathOrClass
+			}
+		}
+		prepared = append(prepared, item)
+	}
+	return prepared, nil
+}
+
+// ValidateReclaimPolicy checks the reclaim policy of the local volumes is one they support
+func ValidateReclaimPolicy(options *ReleaseOptions) error {
+	switch coreV1.PersistentVolumeReclaimPolicy(options.ReclaimPolicy) {
+	case "", coreV1.PersistentVolumeReclaimRetain, coreV1.PersistentVolumeReclaimDelete:
+	case coreV1.PersistentVolumeReclaimRecycle:
+		if options.PVType == "Local" && options.Node != "" {
+			return fmt.Errorf("Reclaim Policy Recycle Not Supported By Local Volumes, use Retain or Delete")
+		}
+	default:
+		return fmt.Errorf("Unknown Reclaim Policy: %s, use Retain, Delete or Recycle", options.ReclaimPolicy)
+	}
+	return nil
+}
+
+// EnsureLocalPath creates the directory of a hostPath PV on this machine
+func EnsureLocalPath(pv *coreV1.PersistentVolume) error {
+	if pv.Spec.HostPath == nil {
+		return nil
+	}
+	dirPath := pv.Spec.HostPath.Path
+	if _, err := os.Stat(dirPath); !os.IsNotExist(err) {
+		return nil
+	}
+	err := os.Mkdir(dirPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(dirPath, os.ModePerm)
+}
+
+// GenerateSecretData fills the Secrets labeled generatedBy: cli with a fresh key pair of keyBits and token
+func GenerateSecretData(secret *coreV1.Secret, keyBits int) error {
+	if secret.Labels["generatedBy"] != "cli" {
+		return nil
+	}
+	keyName := secret.Labels["keyName"]
+	privateKeyPemBlock, publicKeyPemBlock, err := GenerateRSAKeys(keyBits)
+	if err != nil {
+		return err
+	}
+	publicKeyByte := pem.EncodeToMemory(publicKeyPemBlock)
+	privateByte := pem.EncodeToMemory(privateKeyPemBlock)
+	tokenStr, err := SignedToken(keyName, privateByte)
 	if err != nil {
 		return err
 	}
+	secret.Data = map[string][]byte{
+		keyName + ".public.key": publicKeyByte,
+		keyName + ".token": []byte(tokenStr),
+	}
+	return nil
+}
+
+// NewLocalPV returns the PV backing pvc in Local mode, a local PV bound to the node
+// when one is chosen and else a hostPath PV on the machine running the CLI
+func NewLocalPV(pvc *coreV1.PersistentVolumeClaim, options *ReleaseOptions) *coreV1.PersistentVolume {
+	dirName := "funceasy-" + pvc.Name + "-volume"
+	if pvc.Namespace != DefaultNamespace {
+		// PVs are cluster scoped, keep installs in other namespaces apart
+		dirName = "funceasy-" + pvc.Namespace + "-" + pvc.Name + "-volume"
+	}
+	reclaimPolicy := coreV1.PersistentVolumeReclaimPolicy(options.ReclaimPolicy)
+	if reclaimPolicy == "" {
+		reclaimPolicy = DefaultReclaimPolicy
+	}
+	pv := &coreV1.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: dirName,
+			Labels: map[string]string{
+				util.InstanceLabel: options.ReleaseName,
+			},
+		},
+		Spec:       coreV1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			Capacity: pvc.Spec.Resources.Requests,
+			AccessModes: []coreV1.PersistentVolumeAccessMode{
+				coreV1.ReadWriteOnce,
+			},
+			ClaimRef: &coreV1.ObjectReference{
+				Namespace: pvc.Namespace,
+				Name:      pvc.Name,
+			},
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				HostPath:             &coreV1.HostPathVolumeSource{
+					Path: path.Join(options.PathOrClass, dirName),
+				},
+			},
+		},
+	}
+	if options.Node != "" {
+		pv.Spec.PersistentVolumeSource = coreV1.PersistentVolumeSource{
+			Local: &coreV1.LocalVolumeSource{
+				Path: path.Join(options.PathOrClass, dirName),
+			},
+		}
+		pv.Spec.NodeAffinity = LocalVolumeNodeAffinity(options.Node)
+	}
+	return pv
+}
+
+func DeployFuncEasyResources(fileByte []byte, options *ReleaseOptions) error {
+	releaseName := options.ReleaseName
 	t := terminal.NewTerminalPrint()
-	objectList, err := util.ParseK8sYaml(fileByte)
+	objectList, err := PrepareFuncEasyResources(fileByte, options)
+	if err != nil {
+		return err
+	}
+	objectList, err = AdaptCRDVersions(objectList)
+	if err != nil {
+		return err
+	}
+	clientSet, _, err := NewK8sClientSet()
 	if err != nil {
 		return err
 	}
-	clientSet, apiExtensionsClientSet := NewK8sClientSet()
 
-	configMapClient := clientSet.CoreV1().ConfigMaps(NAMESPACE)
-	deploymentClient := clientSet.AppsV1().Deployments(NAMESPACE)
-	serviceClient := clientSet.CoreV1().Services(NAMESPACE)
-	secretClient := clientSet.CoreV1().Secrets(NAMESPACE)
-	PVClient := clientSet.CoreV1().PersistentVolumes()
-	PVCClient := clientSet.CoreV1().PersistentVolumeClaims(NAMESPACE)
-	SAClient := clientSet.CoreV1().ServiceAccounts(NAMESPACE)
-	RoleClient := clientSet.RbacV1().Roles(NAMESPACE)
-	RBClient := clientSet.RbacV1().RoleBindings(NAMESPACE)
-	CRDClient := apiExtensionsClientSet.ApiextensionsV1beta1().CustomResourceDefinitions()
-	var rollback []func() error
-	defer func() {
-		if err := recover(); err != nil {
-			util.Rollback(rollback, t)
+	secretClient := clientSet.CoreV1().Secrets(KubeConfig.Namespace)
+	PVCClient := clientSet.CoreV1().PersistentVolumeClaims(KubeConfig.Namespace)
+	dynamicClient, mapper, err := NewK8sDynamicClient()
+	if err != nil {
+		return err
+	}
+	err = CheckObjectKinds(mapper, objectList)
+	if err != nil {
+		return err
+	}
+	journal := NewJournal()
+	stop := journal.FailOnInterrupt(options.NoRollback)
+	defer stop()
+	namespaceCreated, err := EnsureNamespace(clientSet, KubeConfig.Namespace)
+	if err != nil {
+		return err
+	}
+	if namespaceCreated {
+		namespace := util.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: KubeConfig.Namespace}
+		err = journal.Add(namespace, JournalCreated, util.GenerateDeleteCallback(clientSet.CoreV1().Namespaces().Delete, namespace.Name, &metaV1.DeleteOptions{}))
+		if err != nil {
+			return err
+		}
+	}
+	existing, err := FindExistingObjects(dynamicClient, mapper, objectList, releaseName)
+	if err != nil {
+		return journal.Fail(err, options.NoRollback)
+	}
+	conflicts := ExistingObjectConflicts(existing, options.Resume)
+	if len(conflicts) > 0 {
+		t.PrintErrorOneLine("Conflicting Objects Found: ", len(conflicts))
+		for _, item := range conflicts {
+			fmt.Printf("  %s\n", item)
 		}
-	}()
-	var nodePort = make(map[string]int32)
+		return journal.Fail(fmt.Errorf("Install Aborted: Remove The Conflicting Objects Or Choose Another Release Name"), options.NoRollback)
+	}
+	err = PrepareLocalVolumes(clientSet, objectList, options)
+	if err != nil {
+		return journal.Fail(err, options.NoRollback)
+	}
+	err = PrepareExternalDatabase(clientSet, objectList, options)
+	if err != nil {
+		return journal.Fail(err, options.NoRollback)
+	}
+	var objects []util.ObjectReference
+	waiter := NewRolloutWaiter(clientSet, options.Timeout)
 	for _, item := range objectList {
-		switch item.(type) {
-		case *coreV1.ConfigMap:
-			configMap := item.(*coreV1.ConfigMap)
-			t.PrintInfoOneLine("Creating ConfigMap: %s", configMap.Name)
-			_, err := configMapClient.Create(configMap)
-			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
-			}
-			t.PrintSuccessOneLine("ConfigMap: %s Created", configMap.Name)
-			t.LineEnd()
-			cb := configMapClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, configMap.Name, &metaV1.DeleteOptions{}))
-		case *appsV1.Deployment:
-			deployment := item.(*appsV1.Deployment)
-			t.PrintInfoOneLine("Creating Deployment: %s", deployment.Name)
-			_, err := deploymentClient.Create(deployment)
-			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
-			}
-			t.PrintSuccessOneLine("Deployment: %s Created", deployment.Name)
-			t.LineEnd()
-			cb := deploymentClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, deployment.Name, &metaV1.DeleteOptions{}))
-			if deployment.Name == "funceasy-mysql"  {
-				result := make(chan string)
-				done := make(chan bool)
-				spinningDone := make(chan bool)
-				appLabel := deployment.Spec.Template.Labels["app"]
-				do := CheckMysqlPodsRunning(clientSet, appLabel)
-				t.PrintLoadingOneLine(spinningDone, "Waiting %s Start", deployment.Name)
-				util.PollingCheck(do, 5 * time.Second ,result, done)
-				resultStr := <-result
-				<-time.After(30 * time.Second)
-				spinningDone<-true
-				t.PrintSuccessOneLine("%s Started", deployment.Name)
-				if resultStr == "failed" {
-					t.PrintErrorOneLineWithPanic(err)
+		if found, ok := existing[util.GetObjectReference(item)]; ok {
+			ref := found.Reference
+			if found.Matches || found.Shared {
+				t.PrintWarnOneLine("Adopted %s: %s", ref.Kind, ref.Name)
+				t.LineEnd()
+			} else {
+				t.PrintInfoOneLine("Resuming %s: %s", ref.Kind, ref.Name)
+				err := journal.Apply(ref, JournalModified, func() error {
+					return ReplaceExistingObject(dynamicClient, mapper, found)
+				}, func() error {
+					return RestoreObject(dynamicClient, mapper, found.Live)
+				})
+				if err != nil {
+					return journal.Fail(err, options.NoRollback)
 				}
+				t.PrintSuccessOneLine("%s: %s Updated", ref.Kind, ref.Name)
+				t.LineEnd()
 			}
-		case *coreV1.Service:
-			service := item.(*coreV1.Service)
-			t.PrintInfoOneLine("Creating Service: %s", service.Name)
-			_, err := serviceClient.Create(service)
+			objects = append(objects, ref)
+			continue
+		}
+		if deployment, ok := item.(*appsV1.Deployment); ok {
+			err := waiter.WaitFor(util.DeploymentDependencies(deployment)...)
 			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
+				return journal.Fail(err, options.NoRollback)
 			}
-			t.PrintSuccessOneLine("Service: %s Created", service.Name)
-			t.LineEnd()
-			if service.Spec.Type == coreV1.ServiceTypeNodePort {
-				nodePort[service.Name] = service.Spec.Ports[0].NodePort
+		}
+		if pv, ok := item.(*coreV1.PersistentVolume); ok {
+			err := EnsureLocalPath(pv)
+			if err != nil {
+				return journal.Fail(err, options.NoRollback)
 			}
-			cb := serviceClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, service.Name, &metaV1.DeleteOptions{}))
+		}
+		switch item.(type) {
 		case *coreV1.Secret:
 			secret := item.(*coreV1.Secret)
 			t.PrintInfoOneLine("Creating Secret: %s", secret.Name)
-			if secret.Labels["generatedBy"] == "cli" {
-				keyName := secret.Labels["keyName"]
-				privateKeyPemBlock, publicKeyPemBlock, err := GenerateRSAKeys(1024)
-				if err != nil {
-					t.PrintErrorOneLineWithPanic(err)
-				}
-				publicKeyByte := pem.EncodeToMemory(publicKeyPemBlock)
-				privateByte := pem.EncodeToMemory(privateKeyPemBlock)
-				tokenStr, err := SignedToken(keyName, privateByte)
-				if err != nil {
-					t.PrintErrorOneLineWithPanic(err)
-				}
-				secret.Data = map[string][]byte{
-					keyName + ".public.key": publicKeyByte,
-					keyName + ".token": []byte(tokenStr),
-				}
-			}
-			_, err := secretClient.Create(secret)
+			err := journal.Apply(util.GetObjectReference(secret), JournalCreated, func() error {
+				_, err := secretClient.Create(secret)
+				return err
+			}, util.GenerateDeleteCallback(secretClient.Delete, secret.Name, &metaV1.DeleteOptions{}))
 			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
+				return journal.Fail(err, options.NoRollback)
 			}
 			t.PrintSuccessOneLine("Secret: %s Created", secret.Name)
 			t.LineEnd()
-			cb := secretClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, secret.Name, &metaV1.DeleteOptions{}))
+			objects = append(objects, util.GetObjectReference(secret))
 		case *coreV1.PersistentVolumeClaim:
 			pvc := item.(*coreV1.PersistentVolumeClaim)
-			if PVType == "Local" {
-				dirName := "funceasy-" + pvc.Name + "-volume"
-				dirPath := path.Join(pathOrClass, dirName)
-				if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-					err = os.Mkdir(dirPath, os.ModePerm)
-					if err != nil {
-						t.PrintErrorOneLineWithPanic(err)
-					}
-					err = os.Chmod(dirPath, os.ModePerm)
-					if err != nil {
-						t.PrintErrorOneLineWithPanic(err)
-					}
-				}
-				pv := &coreV1.PersistentVolume{
-					ObjectMeta: metaV1.ObjectMeta{
-						Name: dirName,
-					},
-					Spec:       coreV1.PersistentVolumeSpec{
-						PersistentVolumeReclaimPolicy: coreV1.PersistentVolumeReclaimRecycle,
-						Capacity: pvc.Spec.Resources.Requests,
-						AccessModes: []coreV1.PersistentVolumeAccessMode{
-							coreV1.ReadWriteOnce,
-						},
-						PersistentVolumeSource: coreV1.PersistentVolumeSource{
-							HostPath:             &coreV1.HostPathVolumeSource{
-								Path: dirPath,
-							},
-						},
-					},
-				}
-				t.PrintInfoOneLine("Creating PV: %s", pv.Name)
-				_, err := PVClient.Create(pv)
-				if err != nil {
-					if !errors.IsAlreadyExists(err) {
-						t.PrintErrorOneLineWithPanic(err)
-					}
-					t.PrintWarnOneLine("AlreadyExists PV: %s", pv.Name)
-					t.LineEnd()
-				} else {
-					t.PrintSuccessOneLine("PV: %s Created", pv.Name)
-					t.LineEnd()
-				}
-				cb := PVClient.Delete
-				rollback = append(rollback, util.GenerateDeleteCallback(cb, pv.Name, &metaV1.DeleteOptions{}))
-			}
-			if PVType == "StorageClass" {
-				 pvc.Spec.StorageClassName = &pathOrClass
-			}
 			t.PrintInfoOneLine("Creating PVC: %s", pvc.Name)
-			_, err := PVCClient.Create(pvc)
+			err := journal.Apply(util.GetObjectReference(pvc), JournalCreated, func() error {
+				_, err := PVCClient.Create(pvc)
+				return err
+			}, util.GenerateDeleteCallback(PVCClient.Delete, pvc.Name, &metaV1.DeleteOptions{}))
 			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
+				return journal.Fail(err, options.NoRollback)
 			}
 			t.PrintSuccessOneLine("PVC: %s Created", pvc.Name)
 			t.LineEnd()
-			cb := PVCClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, pvc.Name, &metaV1.DeleteOptions{}))
-		case *coreV1.ServiceAccount:
-			sa := item.(*coreV1.ServiceAccount)
-			t.PrintInfoOneLine("Creating ServiceAccount: %s", sa.Name)
-			_, err := SAClient.Create(sa)
+			objects = append(objects, util.GetObjectReference(pvc))
+		default:
+			obj, err := util.ToUnstructured(item)
 			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
+				return journal.Fail(err, options.NoRollback)
 			}
-			t.PrintSuccessOneLine("ServiceAccount: %s Created", sa.Name)
-			t.LineEnd()
-			cb := SAClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, sa.Name, &metaV1.DeleteOptions{}))
-		case *rbacV1.Role:
-			role := item.(*rbacV1.Role)
-			t.PrintInfoOneLine("Creating Role: %s", role.Name)
-			_, err := RoleClient.Create(role)
+			var c dynamic.ResourceInterface
+			var mapping *meta.RESTMapping
+			c, mapping, mapper, err = RefreshingResourceClient(dynamicClient, mapper, obj, options.Timeout)
 			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
-			}
-			t.PrintSuccessOneLine("Role: %s Created", role.Name)
-			t.LineEnd()
-			cb := RoleClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, role.Name, &metaV1.DeleteOptions{}))
-		case *rbacV1.RoleBinding:
-			rb := item.(*rbacV1.RoleBinding)
-			t.PrintInfoOneLine("Creating RoleBinding: %s", rb.Name)
-			_, err := RBClient.Create(rb)
+				return journal.Fail(err, options.NoRollback)
+			}
+			if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+				obj.SetNamespace("")
+			}
+			kind := obj.GetKind()
+			t.PrintInfoOneLine("Creating %s: %s", kind, obj.GetName())
+			err = journal.Apply(util.GetObjectReference(obj), JournalCreated, func() error {
+				_, err := c.Create(obj, metaV1.CreateOptions{})
+				return err
+			}, util.GenerateDeleteCallback(func(name string, options *metaV1.DeleteOptions) error {
+				return c.Delete(name, options)
+			}, obj.GetName(), &metaV1.DeleteOptions{}))
 			if err != nil {
-				t.PrintErrorOneLineWithPanic(err)
+				if !errors.IsAlreadyExists(err) || mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+					return journal.Fail(err, options.NoRollback)
+				}
+				// cluster scoped objects may be shared with other releases, they are not ours to roll back
+				t.PrintWarnOneLine("AlreadyExists %s: %s", kind, obj.GetName())
+				t.LineEnd()
+				objects = append(objects, util.GetObjectReference(obj))
+				continue
 			}
-			t.PrintSuccessOneLine("RoleBinding: %s Created", rb.Name)
+			t.PrintSuccessOneLine("%s: %s Created", kind, obj.GetName())
 			t.LineEnd()
-			cb := RBClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, rb.Name, &metaV1.DeleteOptions{}))
-		case *v1beta1.CustomResourceDefinition:
-			crd := item.(*v1beta1.CustomResourceDefinition)
-			t.PrintInfoOneLine("Creating CRD: %s", crd.Name)
-			_, err := CRDClient.Create(crd)
-			if err != nil {
-				if !errors.IsAlreadyExists(err) {
-					t.PrintErrorOneLineWithPanic(err)
+			objects = append(objects, util.GetObjectReference(obj))
+			if kind == "CustomResourceDefinition" {
+				err = WaitCRDEstablished(c, obj.GetName(), waiter.timeout)
+				if err != nil {
+					return journal.Fail(err, options.NoRollback)
 				}
-				t.PrintWarnOneLine("AlreadyExists CRD: %s", crd.Name)
-				t.LineEnd()
-			} else {
-				t.PrintSuccessOneLine("CRD: %s Created", crd.Name)
-				t.LineEnd()
 			}
-			cb := CRDClient.Delete
-			rollback = append(rollback, util.GenerateDeleteCallback(cb, crd.Name, &metaV1.DeleteOptions{}))
 		}
 	}
-	for key, value := range nodePort {
-		t.PrintInfoOneLine("Service [%s] exposed on NodePort -> %d", key, value)
-		t.LineEnd()
+	if options.Wait {
+		err := waiter.WaitFor(deploymentNames(objectList)...)
+		if err != nil {
+			return journal.Fail(err, options.NoRollback)
+		}
+	}
+	record := NewReleaseRecord("install", fileByte, objectList, options)
+	record.Objects = objects
+	err = SaveReleaseRecord(clientSet, record)
+	if err != nil {
+		return journal.Fail(fmt.Errorf("Save Release Record Failed: %s", err), options.NoRollback)
 	}
+	pruneReleaseRecords(clientSet, releaseName, options.HistoryMax)
+	printReleaseEndpoints(releaseName)
 	return nil
 }
 
-func UpdateFuncEasyResources(fileByte []byte) error {
-	err := v1beta1.AddToScheme(scheme.Scheme)
+// pruneReleaseRecords warns instead of failing, the release itself is saved
+func pruneReleaseRecords(clientSet *kubernetes.Clientset, releaseName string, historyMax int) {
+	t := terminal.NewTerminalPrint()
+	err := PruneReleaseRecords(clientSet, releaseName, historyMax)
 	if err != nil {
-		return err
+		t.PrintWarnOneLine("Prune Release History Failed: %s", err)
+		t.LineEnd()
+	}
+}
+
+// inheritReleaseOptions takes the storage of the installed revision and the settings the update leaves unset
+func inheritReleaseOptions(options *ReleaseOptions, previous *ReleaseRecord) {
+	if previous == nil {
+		return
+	}
+	options.PVType = previous.PVType
+	options.PathOrClass = previous.PathOrClass
+	options.Node = previous.Node
+	options.ReclaimPolicy = previous.ReclaimPolicy
+	if !options.ResetValues {
+		// reapply the values of the installed revision, the new ones win
+		options.Values = util.MergeValues(previous.Values, options.Values)
+		options.Sets = append(previous.Sets, options.Sets...)
+	}
+	if options.ImageRegistry == "" {
+		options.ImageRegistry = previous.ImageRegistry
+	}
+	if len(options.ImagePullSecrets) == 0 {
+		options.ImagePullSecrets = previous.ImagePullSecrets
+	}
+	if options.Expose == "" {
+		options.Expose = previous.Expose
 	}
+	if options.Host == "" {
+		options.Host = previous.Host
+	}
+	if options.Database == nil {
+		options.Database = previous.Database
+	}
+	if options.Profile == "" {
+		options.Profile = previous.Profile
+	}
+}
+
+func UpdateFuncEasyResources(fileByte []byte, options *ReleaseOptions) error {
+	releaseName := options.ReleaseName
 	t := terminal.NewTerminalPrint()
-	objectList, err := util.ParseK8sYaml(fileByte)
+	clientSet, _, err := NewK8sClientSet()
 	if err != nil {
 		return err
 	}
-	clientSet, apiExtensionsClientSet := NewK8sClientSet()
-
-	configMapClient := clientSet.CoreV1().ConfigMaps(NAMESPACE)
-	deploymentClient := clientSet.AppsV1().Deployments(NAMESPACE)
-	secretClient := clientSet.CoreV1().Secrets(NAMESPACE)
-	serviceClient := clientSet.CoreV1().Services(NAMESPACE)
-	SAClient := clientSet.CoreV1().ServiceAccounts(NAMESPACE)
-	RoleClient := clientSet.RbacV1().Roles(NAMESPACE)
-	RBClient := clientSet.RbacV1().RoleBindings(NAMESPACE)
-	CRDClient := apiExtensionsClientSet.ApiextensionsV1beta1().CustomResourceDefinitions()
-	var nodePort = make(map[string]int32)
+	previous, err := GetLatestReleaseRecord(clientSet, releaseName)
+	if err != nil {
+		return err
+	}
+	inheritReleaseOptions(options, previous)
+	objectList, err := PrepareFuncEasyResources(fileByte, options)
+	if err != nil {
+		return err
+	}
+	objectList, err = AdaptCRDVersions(objectList)
+	if err != nil {
+		return err
+	}
+	dynamicClient, mapper, err := NewK8sDynamicClient()
+	if err != nil {
+		return err
+	}
+	err = CheckObjectKinds(mapper, objectList)
+	if err != nil {
+		return err
+	}
+	breakingChanges, err := CheckCRDUpgrades(dynamicClient, mapper, objectList)
+	if err != nil {
+		return err
+	}
+	if len(breakingChanges) > 0 {
+		for _, change := range breakingChanges {
+			t.PrintWarnOneLine("Breaking Change %s", change)
+			t.LineEnd()
+		}
+		if !options.AllowCRDBreakingChanges {
+			return fmt.Errorf("Update Blocked By %d CRD Breaking Changes, use --allow-crd-breaking-changes to apply them", len(breakingChanges))
+		}
+	}
+	fieldConflicts, err := CheckApplyConflicts(dynamicClient, mapper, objectList)
+	if err != nil {
+		return err
+	}
+	if len(fieldConflicts) > 0 {
+		for _, conflict := range fieldConflicts {
+			t.PrintWarnOneLine("Field Conflict %s", conflict)
+			t.LineEnd()
+		}
+		if !options.ForceConflicts {
+			return fmt.Errorf("Update Blocked By %d Field Conflicts, use --force-conflicts to take the fields over", len(fieldConflicts))
+		}
+	}
+	err = PrepareLocalVolumes(clientSet, objectList, options)
+	if err != nil {
+		return err
+	}
+	err = PrepareExternalDatabase(clientSet, objectList, options)
+	if err != nil {
+		return err
+	}
+	waiter := NewRolloutWaiter(clientSet, options.Timeout)
+	var objects []util.ObjectReference
 	for _, item := range objectList {
-		switch item.(type) {
-		case *coreV1.ConfigMap:
-			configMapNew := item.(*coreV1.ConfigMap)
-			t.PrintInfoOneLine("Updating ConfigMap: %s", configMapNew.Name)
-			configMapOld, err := configMapClient.Get(configMapNew.Name, metaV1.GetOptions{})
+		if deployment, ok := item.(*appsV1.Deployment); ok {
+			err := waiter.WaitFor(util.DeploymentDependencies(deployment)...)
 			if err != nil {
-				if errors.IsNotFound(err) {
-					t.PrintWarnOneLine("ConfigMap Not Found and Creating: %s", configMapNew.Name)
-					_, err := configMapClient.Create(configMapNew)
-					if err != nil {
-						t.PrintErrorOneLineWithExit(err)
-					}
-					t.PrintWarnOneLine("ConfigMap Not Found and Created: %s", configMapNew.Name)
-					t.LineEnd()
-				} else {
-					t.PrintErrorOneLineWithExit(err)
-				}
-			} else {
-				configMapOld.Data = configMapNew.Data
-				_, err = configMapClient.Update(configMapOld)
-				if err != nil {
-					t.PrintErrorOneLineWithExit(err)
-				}
-				t.PrintSuccessOneLine("ConfigMap: %s Updated", configMapNew.Name)
-				t.LineEnd()
-			}
-		case *appsV1.Deployment:
-			deploymentNew := item.(*appsV1.Deployment)
-			t.PrintInfoOneLine("Updating Deployment: %s", deploymentNew.Name)
-			deploymentOld, err := deploymentClient.Get(deploymentNew.Name, metaV1.GetOptions{})
-			if err != nil {
-				if errors.IsNotFound(err) {
-					t.PrintWarnOneLine("Deployment Not Found and Creating: %s", deploymentNew.Name)
-					_, err := deploymentClient.Create(deploymentNew)
-					if err != nil {
-						t.PrintErrorOneLineWithExit(err)
-					}
-					t.PrintWarnOneLine("Deployment Not Found and Created: %s", deploymentNew.Name)
-					t.LineEnd()
-				} else {
-					t.PrintErrorOneLineWithExit(err)
-				}
-			} else {
-				deploymentOld.Spec = deploymentNew.Spec
-				_, err = deploymentClient.Update(deploymentOld)
-				if err != nil {
-					t.PrintErrorOneLineWithExit(err)
-				}
-				t.PrintSuccessOneLine("Deployment: %s Updated", deploymentNew.Name)
-				t.LineEnd()
+				return err
 			}
-			if deploymentNew.Name == "funceasy-mysql" && !reflect.DeepEqual(deploymentNew.Spec, deploymentNew.Spec)  {
-				result := make(chan string)
-				done := make(chan bool)
-				spinningDone := make(chan bool)
-				appLabel := deploymentNew.Spec.Template.Labels["app"]
-				do := CheckMysqlPodsRunning(clientSet, appLabel)
-				util.PollingCheck(do, 5 * time.Second ,result, done)
-				t.PrintLoadingOneLine(spinningDone, "Waiting %s Start", deploymentNew.Name)
-				resultStr := <-result
-				<-time.After(30 * time.Second)
-				spinningDone<-true
-				t.PrintSuccessOneLine("%s Started", deploymentNew.Name)
-				if resultStr == "failed" {
-					t.PrintErrorOneLineWithExit(err)
-				}
+		}
+		obj, err := util.ToUnstructured(item)
+		if err != nil {
+			return err
+		}
+		var c dynamic.ResourceInterface
+		var mapping *meta.RESTMapping
+		c, mapping, mapper, err = RefreshingResourceClient(dynamicClient, mapper, obj, options.Timeout)
+		if err != nil {
+			return err
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			obj.SetNamespace("")
+		}
+		kind := obj.GetKind()
+		t.PrintInfoOneLine("Updating %s: %s", kind, obj.GetName())
+		objects = append(objects, util.GetObjectReference(obj))
+		live, err := c.Get(obj.GetName(), metaV1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return err
 			}
-		case *coreV1.Secret:
-			secret := item.(*coreV1.Secret)
-			t.PrintInfoOneLine("Updating Secret: %s", secret.Name)
-			_, err := secretClient.Get(secret.Name, metaV1.GetOptions{})
-			if err != nil {
-				if errors.IsNotFound(err) {
-					t.PrintWarnOneLine("Secret Not Found and Creating: %s", secret.Name)
-					if secret.Labels["generatedBy"] == "cli" {
-						keyName := secret.Labels["keyName"]
-						privateKeyPemBlock, publicKeyPemBlock, err := GenerateRSAKeys(1024)
-						if err != nil {
-							t.PrintErrorOneLineWithExit(err)
-						}
-						publicKeyByte := pem.EncodeToMemory(publicKeyPemBlock)
-						privateByte := pem.EncodeToMemory(privateKeyPemBlock)
-						tokenStr, err := SignedToken(keyName, privateByte)
-						if err != nil {
-							t.PrintErrorOneLineWithExit(err)
-						}
-						secret.Data = map[string][]byte{
-							keyName + ".public.key": publicKeyByte,
-							keyName + ".token": []byte(tokenStr),
-						}
-					}
-					_, err := secretClient.Create(secret)
-					if err != nil {
-						t.PrintErrorOneLineWithExit(err)
-					}
-					t.PrintWarnOneLine("Deployment Not Found and Created: %s", secret.Name)
-					t.LineEnd()
-				} else {
-					t.PrintErrorOneLineWithExit(err)
+			if pv, ok := item.(*coreV1.PersistentVolume); ok {
+				err := EnsureLocalPath(pv)
+				if err != nil {
+					return err
 				}
 			}
-		case *coreV1.Service:
-			service := item.(*coreV1.Service)
-			t.PrintInfoOneLine("Updating Service: %s", service.Name)
-			err := serviceClient.Delete(service.Name, &metaV1.DeleteOptions{})
-			if err != nil && !errors.IsNotFound(err) {
-				t.PrintErrorOneLineWithExit(err)
-			}
-			_, err = serviceClient.Create(service)
+			t.PrintWarnOneLine("%s Not Found and Creating: %s", kind, obj.GetName())
+			_, err = ApplyObject(c, obj, options.ForceConflicts, false)
 			if err != nil {
-				t.PrintErrorOneLineWithExit(err)
+				return err
 			}
-			t.PrintSuccessOneLine("Service: %s Updated", service.Name)
+			t.PrintWarnOneLine("%s Not Found and Created: %s", kind, obj.GetName())
 			t.LineEnd()
-			if service.Spec.Type == coreV1.ServiceTypeNodePort {
-				nodePort[service.Name] = service.Spec.Ports[0].NodePort
-			}
-		case *coreV1.ServiceAccount:
-			sa := item.(*coreV1.ServiceAccount)
-			t.PrintInfoOneLine("Updating ServiceAccount: %s", sa.Name)
-			err := SAClient.Delete(sa.Name, &metaV1.DeleteOptions{})
-			if err != nil && !errors.IsNotFound(err) {
-				t.PrintErrorOneLineWithExit(err)
-			}
-			_, err = SAClient.Create(sa)
-			if err != nil {
-				t.PrintErrorOneLineWithExit(err)
-			}
-			t.PrintSuccessOneLine("ServiceAccount: %s Updated", sa.Name)
+		} else if UpdateKeeps(obj) {
+			t.PrintSuccessOneLine("%s: %s Kept", kind, obj.GetName())
 			t.LineEnd()
-		case *rbacV1.Role:
-			role := item.(*rbacV1.Role)
-			t.PrintInfoOneLine("Updating Role: %s", role.Name)
-			err := RoleClient.Delete(role.Name, &metaV1.DeleteOptions{})
-			if err != nil && !errors.IsNotFound(err) {
-				t.PrintErrorOneLineWithExit(err)
+			continue
+		} else {
+			var applied *unstructured.Unstructured
+			if kind == "CustomResourceDefinition" {
+				applied, err = ReplaceCRD(c, live, obj, false)
+			} else {
+				applied, err = ApplyObject(c, obj, options.ForceConflicts, false)
 			}
-			_, err = RoleClient.Create(role)
 			if err != nil {
-				t.PrintErrorOneLineWithExit(err)
+				return err
 			}
-			t.PrintSuccessOneLine("Role: %s Updated", role.Name)
-			t.LineEnd()
-		case *rbacV1.RoleBinding:
-			rb := item.(*rbacV1.RoleBinding)
-			t.PrintInfoOneLine("Updating RoleBinding: %s", rb.Name)
-			err := RBClient.Delete(rb.Name, &metaV1.DeleteOptions{})
-			if err != nil && !errors.IsNotFound(err) {
-				t.PrintErrorOneLineWithExit(err)
-			}
-			_, err = RBClient.Create(rb)
-			if err != nil {
-				t.PrintErrorOneLineWithExit(err)
+			if applied.GetResourceVersion() == live.GetResourceVersion() {
+				t.PrintSuccessOneLine("%s: %s Unchanged", kind, obj.GetName())
+			} else {
+				t.PrintSuccessOneLine("%s: %s Updated", kind, obj.GetName())
 			}
-			t.PrintSuccessOneLine("RoleBinding: %s Updated", rb.Name)
 			t.LineEnd()
-		case *v1beta1.CustomResourceDefinition:
-			crd := item.(*v1beta1.CustomResourceDefinition)
-			t.PrintInfoOneLine("Updating CRD: %s", crd.Name)
-			_, err := CRDClient.Get(crd.Name, metaV1.GetOptions{})
+		}
+		if kind == "CustomResourceDefinition" {
+			// the custom resources and the workloads using them need the new definition served
+			err = WaitCRDEstablished(c, obj.GetName(), waiter.timeout)
 			if err != nil {
-				if errors.IsNotFound(err) {
-					_, err = CRDClient.Create(crd)
-					if err != nil {
-						t.PrintErrorOneLineWithExit(err)
-					}
-					t.PrintSuccessOneLine("CRD: %s Updated", crd.Name)
-					t.LineEnd()
-				} else {
-					t.PrintErrorOneLineWithExit(err)
-				}
+				return err
 			}
 		}
 	}
-	for key, value := range nodePort {
-		t.PrintInfoOneLine("Service [%s] exposed on NodePort -> %d", key, value)
-		t.LineEnd()
+	if previous != nil {
+		// update never deletes, objects of the previous revision are still in the cluster
+		objects = util.MergeObjectReferences(objects, previous.Objects)
+	}
+	if options.Wait {
+		err := waiter.WaitFor(deploymentNames(objectList)...)
+		if err != nil {
+			return err
+		}
+	}
+	record := NewReleaseRecord("update", fileByte, objectList, options)
+	record.Objects = objects
+	err = SaveReleaseRecord(clientSet, record)
+	if err != nil {
+		return fmt.Errorf("Save Release Record Failed: %s", err)
 	}
+	pruneReleaseRecords(clientSet, releaseName, options.HistoryMax)
+	printReleaseEndpoints(releaseName)
 	return nil
 }
 
-func GetResourceStatus() {
+func GetResourceStatus(releaseName string) error {
 	t := terminal.NewTerminalPrint()
 	appList := []string{
 		"function-operator",
@@ -492,18 +629,21 @@ func GetResourceStatus() {
 		"funceasy-website",
 	}
 	status := make(map[string][]coreV1.PodPhase)
-	clientSet, _ := NewK8sClientSet()
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return err
+	}
 	for _, item := range appList  {
 		podLabels := metaV1.LabelSelector{
 			MatchLabels: map[string]string{
-				"app": item,
+				"app": util.ReleaseObjectName(releaseName, item),
 			},
 		}
-		pods, err := clientSet.CoreV1().Pods(NAMESPACE).List(metaV1.ListOptions{
+		pods, err := clientSet.CoreV1().Pods(KubeConfig.Namespace).List(metaV1.ListOptions{
 			LabelSelector:       labels.Set(podLabels.MatchLabels).String(),
 		})
 		if err != nil {
-			t.PrintErrorOneLineWithExit(err)
+			return err
 		}
 		var podsStatus []coreV1.PodPhase
 		for _, pod := range pods.Items {
@@ -532,9 +672,13 @@ func GetResourceStatus() {
 		}
 		fmt.Println(str)
 	}
+	return nil
 }
 
-func Restart() {
+// RestartAnnotation on the pod template makes a Deployment replace its pods
+const RestartAnnotation = "funceasy.io/restartedAt"
+
+func Restart(releaseName string, wait bool, timeout time.Duration) error {
 	t := terminal.NewTerminalPrint()
 	appList := []string{
 		"data-source-service",
@@ -542,75 +686,84 @@ func Restart() {
 		"funceasy-api",
 		"funceasy-website",
 	}
-	clientSet, _ := NewK8sClientSet()
-	for _, item := range appList  {
-		t.PrintWarnOneLine("Restarting %s", item)
-		podLabels := metaV1.LabelSelector{
-			MatchLabels: map[string]string{
-				"app": item,
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						RestartAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
 			},
-		}
-		pods, err := clientSet.CoreV1().Pods(NAMESPACE).List(metaV1.ListOptions{
-			LabelSelector:       labels.Set(podLabels.MatchLabels).String(),
-		})
+		},
+	})
+	if err != nil {
+		return err
+	}
+	var restarted []string
+	for _, item := range appList  {
+		name := util.ReleaseObjectName(releaseName, item)
+		t.PrintWarnOneLine("Restarting %s", name)
+		_, err := clientSet.AppsV1().Deployments(KubeConfig.Namespace).Patch(name, types.StrategicMergePatchType, patch)
 		if err != nil {
-			t.PrintErrorOneLineWithExit(err)
-		}
-		for _, pod := range pods.Items {
-			err := clientSet.CoreV1().Pods(NAMESPACE).Delete(pod.Name, &metaV1.DeleteOptions{})
-			if err != nil {
-				t.PrintErrorOneLineWithExit(err)
+			if errors.IsNotFound(err) {
+				t.PrintWarnOneLine("Deployment Not Found: %s", name)
+				t.LineEnd()
+				continue
 			}
+			return err
 		}
-		t.PrintSuccessOneLine("Restarted %s", item)
+		restarted = append(restarted, name)
+		t.PrintSuccessOneLine("Restarted %s", name)
 		t.LineEnd()
 	}
+	if wait {
+		return NewRolloutWaiter(clientSet, timeout).WaitFor(restarted...)
+	}
+	return nil
 }
 
-func GetCurrentVersion() string {
-	clientSet, _ := NewK8sClientSet()
-	t := terminal.NewTerminalPrint()
-	cm, err := clientSet.CoreV1().ConfigMaps("funceasy").Get("funceasy-config", metaV1.GetOptions{})
+func GetCurrentVersion(releaseName string) (string, error) {
+	clientSet, _, err := NewK8sClientSet()
 	if err != nil {
-		if !errors.IsNotFound(err) {
-			t.PrintErrorOneLine("Get Current Version Failed \n", err)
-		} else {
-			return ""
+		return "", err
+	}
+	cm, err := clientSet.CoreV1().ConfigMaps(KubeConfig.Namespace).Get(util.ReleaseObjectName(releaseName, "funceasy-config"), metaV1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
 		}
+		return "", fmt.Errorf("Get Current Version Failed: %s", err)
 	}
-	return cm.Data["version"]
+	return cm.Data["version"], nil
 }
 
-func CheckMysqlPodsRunning(clientSet *kubernetes.Clientset, appLabel string) func(result chan string, done chan bool) error {
-	podLabels := metaV1.LabelSelector{
-		MatchLabels: map[string]string{
-			"app": appLabel,
+func EnsureNamespace(clientSet *kubernetes.Clientset, namespace string) (bool, error) {
+	t := terminal.NewTerminalPrint()
+	_, err := clientSet.CoreV1().Namespaces().Get(namespace, metaV1.GetOptions{})
+	if err == nil {
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+	t.PrintInfoOneLine("Creating Namespace: %s", namespace)
+	_, err = clientSet.CoreV1().Namespaces().Create(&coreV1.Namespace{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: namespace,
 		},
+	})
+	if errors.IsAlreadyExists(err) {
+		return false, nil
 	}
-	return func(result chan string, done chan bool) error {
-		pods, err := clientSet.CoreV1().Pods(NAMESPACE).List(metaV1.ListOptions{
-			LabelSelector:      labels.Set(podLabels.MatchLabels).String(),
-		})
-		if err != nil {
-			return err
-		}
-		if len(pods.Items) > 0 {
-			pod := pods.Items[0]
-			if pod.Status.Phase == coreV1.PodRunning {
-				for _, item := range pod.Status.Conditions {
-					if item.Type == coreV1.PodReady && item.Status == coreV1.ConditionTrue {
-						done<-true
-						result<-"success"
-					}
-				}
-			}
-			if pod.Status.Phase == coreV1.PodFailed {
-				done<-true
-				result<-"failed"
-			}
-		} else {
-			return nil
-		}
-		return nil
+	if err != nil {
+		return false, err
 	}
+	t.PrintSuccessOneLine("Namespace: %s Created", namespace)
+	t.LineEnd()
+	return true, nil
 }
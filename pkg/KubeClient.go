@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdApi "k8s.io/client-go/tools/clientcmd/api"
+	"strings"
+	"time"
+)
+
+// KubeConfigFlags holds the global flags selecting the cluster and identity to talk to
+type KubeConfigFlags struct {
+	KubeConfig  string
+	Context     string
+	Cluster     string
+	User        string
+	Impersonate string
+	Namespace   string
+}
+
+// KubeConfig is filled by the root command persistent flags
+var KubeConfig = &KubeConfigFlags{Namespace: DefaultNamespace}
+
+// NewK8sRestConfig loads the client config from --kubeconfig, the merged KUBECONFIG
+// path list or ~/.kube/config, and falls back to the in-cluster service account
+func NewK8sRestConfig() (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = KubeConfig.KubeConfig
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: KubeConfig.Context,
+		Context: clientcmdApi.Context{
+			Cluster:  KubeConfig.Cluster,
+			AuthInfo: KubeConfig.User,
+		},
+		AuthInfo: clientcmdApi.AuthInfo{
+			Impersonate: KubeConfig.Impersonate,
+		},
+	}
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// the API server names the field manager of the create and update calls after the user agent
+	cfg.UserAgent = FieldManager + "/" + CLIVersion
+	if KubeConfig.Impersonate != "" {
+		// the in-cluster config ignores the AuthInfo overrides
+		cfg.Impersonate.UserName = KubeConfig.Impersonate
+	}
+	return cfg, nil
+}
+
+func NewK8sClientSet() (kubernetesClient *kubernetes.Clientset, apiExtensionsClient *apiextensionsclient.Clientset, err error) {
+	cfg, err := NewK8sRestConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	clientSet, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiExtensionsClientSet, err := apiextensionsclient.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientSet, apiExtensionsClientSet, nil
+}
+
+func NewK8sDiscoveryClient() (*discovery.DiscoveryClient, error) {
+	cfg, err := NewK8sRestConfig()
+	if err != nil {
+		return nil, err
+	}
+	return discovery.NewDiscoveryClientForConfig(cfg)
+}
+
+// NewK8sDynamicClient returns a dynamic client and a RESTMapper built from the server discovery
+func NewK8sDynamicClient() (dynamic.Interface, meta.RESTMapper, error) {
+	cfg, err := NewK8sRestConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	groupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dynamicClient, restmapper.NewDiscoveryRESTMapper(groupResources), nil
+}
+
+// ResourceClient returns the dynamic client for the resource and scope of obj
+func ResourceClient(dynamicClient dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()), mapping, nil
+	}
+	return dynamicClient.Resource(mapping.Resource), mapping, nil
+}
+
+// RefreshingResourceClient is ResourceClient for kinds the mapper may not know yet, such as the
+// custom resources of a CRD created moments ago. It rebuilds the mapper from the discovery until
+// the kind is served or the timeout passes, and returns the mapper to use from then on.
+func RefreshingResourceClient(dynamicClient dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured, timeout time.Duration) (dynamic.ResourceInterface, *meta.RESTMapping, meta.RESTMapper, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		c, mapping, err := ResourceClient(dynamicClient, mapper, obj)
+		if err == nil || !meta.IsNoMatchError(err) || time.Now().After(deadline) {
+			return c, mapping, mapper, err
+		}
+		time.Sleep(time.Second)
+		_, mapper, err = NewK8sDynamicClient()
+		if err != nil {
+			return nil, nil, mapper, err
+		}
+	}
+}
+
+// CheckObjectKinds fails for the objects whose kind the cluster does not serve,
+// unless a CustomResourceDefinition of the objects defines it
+func CheckObjectKinds(mapper meta.RESTMapper, objectList []runtime.Object) error {
+	defined := make(map[string]bool)
+	for _, item := range objectList {
+		if util.GetObjectReference(item).Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crd, err := util.ToUnstructured(item)
+		if err != nil {
+			return err
+		}
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		defined[kind+"."+group] = true
+	}
+	var unsupported []string
+	for _, item := range objectList {
+		ref := util.GetObjectReference(item)
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		_, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err == nil || defined[gvk.GroupKind().String()] {
+			continue
+		}
+		if !meta.IsNoMatchError(err) {
+			return err
+		}
+		unsupported = append(unsupported, fmt.Sprintf("%s %s (%s)", ref.Kind, ref.Name, ref.APIVersion))
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("Kinds Not Served By The Cluster: %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
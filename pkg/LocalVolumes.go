@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultReclaimPolicy of the local PVs, Recycle is deprecated and local volumes do not support it
+const DefaultReclaimPolicy = coreV1.PersistentVolumeReclaimRetain
+
+// PrepareVolumesImage runs the Job creating the local volume directories on the node
+const PrepareVolumesImage = "busybox:1.31"
+
+const prepareVolumesMountPath = "/funceasy-data"
+
+// LocalVolumeNodeAffinity pins a local PV to the node through its hostname label
+func LocalVolumeNodeAffinity(node string) *coreV1.VolumeNodeAffinity {
+	return &coreV1.VolumeNodeAffinity{
+		Required: &coreV1.NodeSelector{
+			NodeSelectorTerms: []coreV1.NodeSelectorTerm{
+				{
+					MatchExpressions: []coreV1.NodeSelectorRequirement{
+						{
+							Key:      coreV1.LabelHostname,
+							Operator: coreV1.NodeSelectorOpIn,
+							Values:   []string{node},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// localVolumeDirs returns the directories of the local PVs as the Jobs mount them
+func localVolumeDirs(pvs []*coreV1.PersistentVolume) string {
+	var dirs []string
+	for _, pv := range pvs {
+		dirs = append(dirs, path.Join(prepareVolumesMountPath, path.Base(pv.Spec.Local.Path)))
+	}
+	return strings.Join(dirs, " ")
+}
+
+// NewPrepareVolumesJob returns the privileged Job creating the directories of the local PVs on the node
+func NewPrepareVolumesJob(pvs []*coreV1.PersistentVolume, options *ReleaseOptions) *batchV1.Job {
+	dirs := localVolumeDirs(pvs)
+	return newLocalVolumesJob("prepare", "mkdir -p "+dirs+" && chmod 777 "+dirs, options)
+}
+
+// NewRemoveVolumesJob returns the privileged Job removing the directories of the local PVs from the node
+func NewRemoveVolumesJob(pvs []*coreV1.PersistentVolume, options *ReleaseOptions) *batchV1.Job {
+	return newLocalVolumesJob("remove", "rm -rf "+localVolumeDirs(pvs), options)
+}
+
+// newLocalVolumesJob returns a privileged Job running command on the node, the local directory of options mounted
+func newLocalVolumesJob(action string, command string, options *ReleaseOptions) *batchV1.Job {
+	backoffLimit := int32(2)
+	privileged := true
+	directoryOrCreate := coreV1.HostPathDirectoryOrCreate
+	return &batchV1.Job{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      util.ReleaseObjectName(options.ReleaseName, "funceasy-"+action+"-volumes"),
+			Namespace: KubeConfig.Namespace,
+			Labels: map[string]string{
+				util.InstanceLabel: options.ReleaseName,
+			},
+		},
+		Spec: batchV1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: coreV1.PodTemplateSpec{
+				Spec: coreV1.PodSpec{
+					NodeName:      options.Node,
+					RestartPolicy: coreV1.RestartPolicyNever,
+					// the volumes may live on a tainted node, the control plane of a small cluster
+					Tolerations: []coreV1.Toleration{
+						{Operator: coreV1.TolerationOpExists},
+					},
+					ImagePullSecrets: jobPullSecrets(options),
+					Containers: []coreV1.Container{
+						{
+							Name:    action,
+							Image:   util.MirrorImage(PrepareVolumesImage, options.ImageRegistry),
+							Command: []string{"sh", "-c", command},
+							SecurityContext: &coreV1.SecurityContext{
+								Privileged: &privileged,
+							},
+							VolumeMounts: []coreV1.VolumeMount{
+								{Name: "data", MountPath: prepareVolumesMountPath},
+							},
+						},
+					},
+					Volumes: []coreV1.Volume{
+						{
+							Name: "data",
+							VolumeSource: coreV1.VolumeSource{
+								HostPath: &coreV1.HostPathVolumeSource{
+									Path: options.PathOrClass,
+									Type: &directoryOrCreate,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// PrepareLocalVolumes creates the directories of the local PVs on their node with a Job.
+// The hostPath PVs are handled by EnsureLocalPath
+func PrepareLocalVolumes(clientSet *kubernetes.Clientset, objectList []runtime.Object, options *ReleaseOptions) error {
+	var pvs []*coreV1.PersistentVolume
+	for _, item := range objectList {
+		if pv, ok := item.(*coreV1.PersistentVolume); ok && pv.Spec.Local != nil {
+			pvs = append(pvs, pv)
+		}
+	}
+	if len(pvs) == 0 {
+		return nil
+	}
+	t := terminal.NewTerminalPrint()
+	t.PrintInfoOneLine("Preparing Local Volumes On Node: %s", options.Node)
+	err := RunJob(clientSet, NewPrepareVolumesJob(pvs, options), options.Timeout)
+	if err != nil {
+		return fmt.Errorf("Prepare Local Volumes Failed: %s", err)
+	}
+	t.PrintSuccessOneLine("Local Volumes Prepared On Node: %s", options.Node)
+	t.LineEnd()
+	return nil
+}
+
+// localVolumeNode returns the node the affinity of a local PV pins it to
+func localVolumeNode(pv *coreV1.PersistentVolume) string {
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return ""
+	}
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == coreV1.LabelHostname && len(expression.Values) > 0 {
+				return expression.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// RemoveLocalVolumes removes the directories of the deleted local PVs from their node with a Job per node
+// and local directory. The images and pull secrets of the record are used when there is one, a failed
+// Job leaves the data and tells where it is
+func RemoveLocalVolumes(clientSet *kubernetes.Clientset, pvs []*coreV1.PersistentVolume, releaseName string, record *ReleaseRecord, timeout time.Duration) {
+	t := terminal.NewTerminalPrint()
+	groups := make(map[string][]*coreV1.PersistentVolume)
+	var keys []string
+	for _, pv := range pvs {
+		key := localVolumeNode(pv) + ":" + path.Dir(pv.Spec.Local.Path)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], pv)
+	}
+	for _, key := range keys {
+		group := groups[key]
+		options := &ReleaseOptions{
+			ReleaseName: releaseName,
+			Node:        localVolumeNode(group[0]),
+			PathOrClass: path.Dir(group[0].Spec.Local.Path),
+		}
+		if record != nil {
+			options.ImageRegistry = record.ImageRegistry
+			options.ImagePullSecrets = record.ImagePullSecrets
+		}
+		var dirs []string
+		for _, pv := range group {
+			dirs = append(dirs, pv.Spec.Local.Path)
+		}
+		if options.Node == "" {
+			t.PrintWarnOneLine("Local Data Left, No Node Found For: %s", strings.Join(dirs, ", "))
+			t.LineEnd()
+			continue
+		}
+		t.PrintInfoOneLine("Removing Local Data On Node %s: %s", options.Node, strings.Join(dirs, ", "))
+		err := RunJob(clientSet, NewRemoveVolumesJob(group, options), timeout)
+		if err != nil {
+			t.PrintWarnOneLine("Remove Local Data Failed: %s, Left On Node %s: %s", err, options.Node, strings.Join(dirs, ", "))
+			t.LineEnd()
+			continue
+		}
+		t.PrintSuccessOneLine("Local Data On Node %s: %s Removed", options.Node, strings.Join(dirs, ", "))
+		t.LineEnd()
+	}
+}
@@ -0,0 +1,462 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"io/ioutil"
+	authorizationV1 "k8s.io/api/authorization/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	PreflightPass = "PASS"
+	PreflightWarn = "WARN"
+	PreflightFail = "FAIL"
+)
+
+// MinServerMinor is the oldest Kubernetes 1.x minor version the manifests run on
+const MinServerMinor = 14
+
+// ClientMinor is the Kubernetes 1.x minor version of the vendored client-go
+const ClientMinor = 17
+
+// PreflightVerbs are the verbs install and update run on the manifest kinds: update for --resume
+// and the rollback restoring objects, patch for the server-side apply, delete for the rollback
+var PreflightVerbs = []string{"get", "create", "update", "patch", "delete"}
+
+type PreflightResult struct {
+	Check   string
+	Status  string
+	Message string
+}
+
+// PreflightReport collects the results of the checks run before an install
+type PreflightReport struct {
+	Results []PreflightResult
+}
+
+func (r *PreflightReport) add(check string, status string, format string, a ...interface{}) {
+	r.Results = append(r.Results, PreflightResult{
+		Check:   check,
+		Status:  status,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// Failed counts the failed checks
+func (r *PreflightReport) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Status == PreflightFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Print writes the report as a table, one check per row
+func (r *PreflightReport) Print() {
+	width := len("CHECK")
+	for _, result := range r.Results {
+		if len(result.Check) > width {
+			width = len(result.Check)
+		}
+	}
+	fmt.Printf("%-6s  %-*s  %s\n", "STATUS", width, "CHECK", "MESSAGE")
+	for _, result := range r.Results {
+		status := fmt.Sprintf("%-6s  ", result.Status)
+		switch result.Status {
+		case PreflightPass:
+			status = color.HiGreenString(status)
+		case PreflightWarn:
+			status = color.YellowString(status)
+		case PreflightFail:
+			status = color.HiRedString(status)
+		}
+		fmt.Printf("%s%-*s  %s\n", status, width, result.Check, result.Message)
+	}
+}
+
+// Preflight checks the cluster can take the install, prints the report and fails when a check fails
+func Preflight(fileByte []byte, options *ReleaseOptions) error {
+	report, err := RunPreflight(fileByte, options)
+	if err != nil {
+		return err
+	}
+	report.Print()
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("Preflight Failed: %d Checks Failed", failed)
+	}
+	return nil
+}
+
+// RunPreflight checks the server version, the APIs and permissions the manifest needs,
+// the storage, the node ports and the node capacity
+func RunPreflight(fileByte []byte, options *ReleaseOptions) (*PreflightReport, error) {
+	// parse only, preparing the objects generates the key pairs of the Secrets
+	err := ValidateReclaimPolicy(options)
+	if err != nil {
+		return nil, err
+	}
+	objectList, err := ParseFuncEasyResources(fileByte, options)
+	if err != nil {
+		return nil, err
+	}
+	objectList, err = AdaptCRDVersions(objectList)
+	if err != nil {
+		return nil, err
+	}
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return nil, err
+	}
+	_, mapper, err := NewK8sDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	report := &PreflightReport{}
+	checkServerVersion(report, clientSet)
+	err = CheckObjectKinds(mapper, objectList)
+	if err != nil {
+		report.add("APIs", PreflightFail, "%s", err)
+	} else {
+		report.add("APIs", PreflightPass, "every manifest kind is served")
+	}
+	checkPermissions(report, clientSet, mapper, objectList, releasePermissionTargets(clientSet, options))
+	switch options.PVType {
+	case "StorageClass":
+		checkStorageClass(report, clientSet, options.PathOrClass)
+	case "Local":
+		if options.Node != "" {
+			checkNode(report, clientSet, options.Node)
+		} else {
+			checkLocalPath(report, options.PathOrClass)
+		}
+	}
+	checkNodePorts(report, clientSet, objectList)
+	checkCapacity(report, clientSet, objectList)
+	return report, nil
+}
+
+func checkServerVersion(report *PreflightReport, clientSet *kubernetes.Clientset) {
+	info, err := clientSet.Discovery().ServerVersion()
+	if err != nil {
+		report.add("Server Version", PreflightFail, "%s", err)
+		return
+	}
+	minor, err := strconv.Atoi(strings.TrimRight(info.Minor, "+"))
+	if err != nil || info.Major != "1" {
+		report.add("Server Version", PreflightWarn, "%s: unknown version", info.GitVersion)
+	} else if minor < MinServerMinor {
+		report.add("Server Version", PreflightFail, "%s: 1.%d or newer needed", info.GitVersion, MinServerMinor)
+	} else if minor > ClientMinor+1 {
+		report.add("Server Version", PreflightWarn, "%s: newer than the supported 1.%d", info.GitVersion, ClientMinor+1)
+	} else {
+		report.add("Server Version", PreflightPass, "%s", info.GitVersion)
+	}
+}
+
+// permissionTarget is a resource the install runs verbs on
+type permissionTarget struct {
+	kind       string
+	group      string
+	resource   string
+	namespaced bool
+	verbs      []string
+}
+
+// releasePermissionTargets are the resources the CLI uses beside the manifest kinds: the namespace it
+// creates when missing, the release record Secrets, the Jobs preparing the node local volumes and
+// checking the external database, and the local volumes it adds to the manifest
+func releasePermissionTargets(clientSet *kubernetes.Clientset, options *ReleaseOptions) []permissionTarget {
+	targets := []permissionTarget{
+		{"Release Record", "", "secrets", true, []string{"get", "list", "create", "delete"}},
+	}
+	_, err := clientSet.CoreV1().Namespaces().Get(KubeConfig.Namespace, metaV1.GetOptions{})
+	if errors.IsNotFound(err) {
+		targets = append(targets, permissionTarget{"Namespace", "", "namespaces", false, []string{"create"}})
+	}
+	if options.PVType == "Local" {
+		targets = append(targets, permissionTarget{"PersistentVolume", "", "persistentvolumes", false, PreflightVerbs})
+	}
+	if options.PVType == "Local" && options.Node != "" || options.Database != nil {
+		targets = append(targets, permissionTarget{"Job", "batch", "jobs", true, []string{"get", "create", "delete"}})
+	}
+	return targets
+}
+
+// checkPermissions asks the server whether the caller may run the install verbs on each manifest kind
+// and the verbs of the release targets on theirs
+func checkPermissions(report *PreflightReport, clientSet *kubernetes.Clientset, mapper meta.RESTMapper,
+	objectList []runtime.Object, releaseTargets []permissionTarget) {
+	// the kinds the manifest CRDs define are not mapped before the CRDs are created
+	type resourceScope struct {
+		resource   string
+		namespaced bool
+	}
+	defined := make(map[schema.GroupKind]resourceScope)
+	for _, item := range objectList {
+		if util.GetObjectReference(item).Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crd, err := util.ToUnstructured(item)
+		if err != nil {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		scope, _, _ := unstructured.NestedString(crd.Object, "spec", "scope")
+		defined[schema.GroupKind{Group: group, Kind: kind}] = resourceScope{plural, scope != "Cluster"}
+	}
+	checked := make(map[schema.GroupKind]bool)
+	var targets []permissionTarget
+	for _, item := range objectList {
+		ref := util.GetObjectReference(item)
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		if checked[gvk.GroupKind()] {
+			continue
+		}
+		checked[gvk.GroupKind()] = true
+		target, ok := defined[gvk.GroupKind()]
+		if !ok {
+			mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				// the APIs check reports the kinds not served
+				continue
+			}
+			target = resourceScope{mapping.Resource.Resource, mapping.Scope.Name() == meta.RESTScopeNameNamespace}
+		}
+		targets = append(targets, permissionTarget{ref.Kind, gvk.Group, target.resource, target.namespaced, PreflightVerbs})
+	}
+	targets = append(targets, releaseTargets...)
+	problems := 0
+	for _, target := range targets {
+		namespace := ""
+		if target.namespaced {
+			namespace = KubeConfig.Namespace
+		}
+		var denied []string
+		for _, verb := range target.verbs {
+			review, err := clientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationV1.SelfSubjectAccessReview{
+				Spec: authorizationV1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationV1.ResourceAttributes{
+						Namespace: namespace,
+						Verb:      verb,
+						Group:     target.group,
+						Resource:  target.resource,
+					},
+				},
+			})
+			if err != nil {
+				report.add("Permission "+target.kind, PreflightWarn, "can not review access: %s", err)
+				problems++
+				denied = nil
+				break
+			}
+			if !review.Status.Allowed {
+				denied = append(denied, verb)
+			}
+		}
+		if len(denied) > 0 {
+			report.add("Permission "+target.kind, PreflightFail, "%s denied on %s", strings.Join(denied, ", "), target.resource)
+			problems++
+		}
+	}
+	if problems > 0 {
+		return
+	}
+	report.add("Permissions", PreflightPass, "%d manifest kinds reviewed for %s, %d release resources for their verbs",
+		len(checked), strings.Join(PreflightVerbs, ", "), len(releaseTargets))
+}
+
+func checkStorageClass(report *PreflightReport, clientSet *kubernetes.Clientset, name string) {
+	_, err := clientSet.StorageV1().StorageClasses().Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			report.add("Storage Class", PreflightFail, "%s not found", name)
+		} else {
+			report.add("Storage Class", PreflightWarn, "%s", err)
+		}
+		return
+	}
+	report.add("Storage Class", PreflightPass, "%s", name)
+}
+
+// checkNode checks the node of the local volumes can run the Job preparing their directories
+func checkNode(report *PreflightReport, clientSet *kubernetes.Clientset, name string) {
+	node, err := clientSet.CoreV1().Nodes().Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			report.add("Local Node", PreflightFail, "%s not found", name)
+		} else {
+			report.add("Local Node", PreflightWarn, "%s", err)
+		}
+		return
+	}
+	if hostname := node.Labels[coreV1.LabelHostname]; hostname != name {
+		report.add("Local Node", PreflightFail, "%s has the hostname label %s, the volumes select it by hostname", name, hostname)
+		return
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == coreV1.NodeReady && condition.Status != coreV1.ConditionTrue {
+			report.add("Local Node", PreflightFail, "%s is not ready", name)
+			return
+		}
+	}
+	report.add("Local Node", PreflightPass, "%s", name)
+}
+
+// checkLocalPath checks the directory the local volumes are created in, see EnsureLocalPath
+func checkLocalPath(report *PreflightReport, dirPath string) {
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		report.add("Local Path", PreflightFail, "%s", err)
+		return
+	}
+	if !info.IsDir() {
+		report.add("Local Path", PreflightFail, "%s is not a directory", dirPath)
+		return
+	}
+	file, err := ioutil.TempFile(dirPath, ".funceasy-preflight")
+	if err != nil {
+		report.add("Local Path", PreflightFail, "%s is not writable: %s", dirPath, err)
+		return
+	}
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+	report.add("Local Path", PreflightPass, "%s", dirPath)
+}
+
+// checkNodePorts fails for the node ports the manifest requests that another Service holds
+func checkNodePorts(report *PreflightReport, clientSet *kubernetes.Clientset, objectList []runtime.Object) {
+	requested := make(map[int32]string)
+	for _, item := range objectList {
+		if service, ok := item.(*coreV1.Service); ok {
+			for _, port := range service.Spec.Ports {
+				if port.NodePort != 0 {
+					requested[port.NodePort] = service.Namespace + "/" + service.Name
+				}
+			}
+		}
+	}
+	if len(requested) == 0 {
+		report.add("Node Ports", PreflightPass, "none requested")
+		return
+	}
+	services, err := clientSet.CoreV1().Services(metaV1.NamespaceAll).List(metaV1.ListOptions{})
+	if err != nil {
+		report.add("Node Ports", PreflightWarn, "can not list the Services: %s", err)
+		return
+	}
+	var used []string
+	for _, service := range services.Items {
+		name := service.Namespace + "/" + service.Name
+		for _, port := range service.Spec.Ports {
+			if owner, ok := requested[port.NodePort]; ok && port.NodePort != 0 && owner != name {
+				used = append(used, fmt.Sprintf("%d by %s", port.NodePort, name))
+			}
+		}
+	}
+	if len(used) > 0 {
+		report.add("Node Ports", PreflightFail, "in use: %s", strings.Join(used, ", "))
+		return
+	}
+	report.add("Node Ports", PreflightPass, "%d free", len(requested))
+}
+
+// podRequests sums the cpu and memory requests of a pod, an init container runs alone
+func podRequests(spec *coreV1.PodSpec) coreV1.ResourceList {
+	requests := coreV1.ResourceList{}
+	for _, name := range []coreV1.ResourceName{coreV1.ResourceCPU, coreV1.ResourceMemory} {
+		total := resource.Quantity{}
+		for _, container := range spec.Containers {
+			if value, ok := container.Resources.Requests[name]; ok {
+				total.Add(value)
+			}
+		}
+		for _, container := range spec.InitContainers {
+			if value, ok := container.Resources.Requests[name]; ok && value.Cmp(total) > 0 {
+				total = value.DeepCopy()
+			}
+		}
+		requests[name] = total
+	}
+	return requests
+}
+
+func addResources(total coreV1.ResourceList, add coreV1.ResourceList, times int64) {
+	for name, value := range add {
+		sum := total[name]
+		for i := int64(0); i < times; i++ {
+			sum.Add(value)
+		}
+		total[name] = sum
+	}
+}
+
+// checkCapacity compares the requests of the manifest pods with the allocatable capacity of the schedulable nodes
+func checkCapacity(report *PreflightReport, clientSet *kubernetes.Clientset, objectList []runtime.Object) {
+	nodes, err := clientSet.CoreV1().Nodes().List(metaV1.ListOptions{})
+	if err != nil {
+		report.add("Capacity", PreflightWarn, "can not list the nodes: %s", err)
+		return
+	}
+	allocatable := coreV1.ResourceList{}
+	schedulable := make(map[string]bool)
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		schedulable[node.Name] = true
+		addResources(allocatable, node.Status.Allocatable, 1)
+	}
+	requests := coreV1.ResourceList{}
+	for _, item := range objectList {
+		template, _ := util.PodTemplate(item)
+		if template == nil {
+			continue
+		}
+		addResources(requests, podRequests(&template.Spec), int64(util.PodReplicas(item, int32(len(schedulable)))))
+	}
+	used := coreV1.ResourceList{}
+	pods, err := clientSet.CoreV1().Pods(metaV1.NamespaceAll).List(metaV1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		report.add("Capacity", PreflightWarn, "can not list the pods: %s", err)
+	} else {
+		for _, pod := range pods.Items {
+			if schedulable[pod.Spec.NodeName] {
+				addResources(used, podRequests(&pod.Spec), 1)
+			}
+		}
+	}
+	status := PreflightPass
+	var messages []string
+	for _, name := range []coreV1.ResourceName{coreV1.ResourceCPU, coreV1.ResourceMemory} {
+		request := requests[name]
+		total := allocatable[name]
+		free := total.DeepCopy()
+		free.Sub(used[name])
+		if request.Cmp(total) > 0 {
+			status = PreflightFail
+		} else if request.Cmp(free) > 0 && status != PreflightFail {
+			status = PreflightWarn
+		}
+		messages = append(messages, fmt.Sprintf("%s %s requested, %s of %s free", name, request.String(), free.String(), total.String()))
+	}
+	report.add("Capacity", status, "%s", strings.Join(messages, "; "))
+}
@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/spf13/viper"
+	"sort"
+	"strings"
+)
+
+// ProfilesConfigKey holds the custom profiles in the CLI config file, by name:
+//
+//	profiles:
+//	  staging:
+//	    base: production
+//	    replicas: 1
+//	    expose: ingress
+const ProfilesConfigKey = "profiles"
+
+// GetProfile resolves a built-in profile or a custom one of the config file, nil for no name
+func GetProfile(name string) (*util.Profile, error) {
+	return getProfile(name, map[string]bool{})
+}
+
+func getProfile(name string, seen map[string]bool) (*util.Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	// the config keys are case insensitive, the built-in names are lower case too
+	name = strings.ToLower(name)
+	if seen[name] {
+		return nil, fmt.Errorf("Profile %s Extends Itself", name)
+	}
+	seen[name] = true
+	custom := viper.GetStringMap(ProfilesConfigKey)
+	if config, ok := custom[name]; ok {
+		// json matches the fields case insensitively like the config keys
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		profile := util.Profile{}
+		err = json.Unmarshal(data, &profile)
+		if err != nil {
+			return nil, fmt.Errorf("Decode Profile %s Failed: %s", name, err)
+		}
+		base := &util.Profile{}
+		if profile.Base != "" {
+			base, err = getProfile(profile.Base, seen)
+			if err != nil {
+				return nil, err
+			}
+		}
+		merged := base.Merge(profile)
+		return &merged, nil
+	}
+	if profile, ok := util.Profiles[name]; ok {
+		return &profile, nil
+	}
+	return nil, fmt.Errorf("Profile Not Found: %s, use one of %s", name, strings.Join(ProfileNames(), ", "))
+}
+
+// ProfileNames lists the built-in profiles and the ones of the config file
+func ProfileNames() []string {
+	var names []string
+	for key := range util.Profiles {
+		names = append(names, key)
+	}
+	for key := range viper.GetStringMap(ProfilesConfigKey) {
+		if _, ok := util.Profiles[key]; !ok {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	appsV1 "k8s.io/api/apps/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"time"
+)
+
+const DefaultRolloutTimeout = 5 * time.Minute
+
+const rolloutPollInterval = 2 * time.Second
+
+// RolloutWaiter waits for Deployment rollouts, every wait of one command shares the timeout
+type RolloutWaiter struct {
+	clientSet *kubernetes.Clientset
+	timeout   time.Duration
+	deadline  time.Time
+	done      map[string]bool
+}
+
+func NewRolloutWaiter(clientSet *kubernetes.Clientset, timeout time.Duration) *RolloutWaiter {
+	if timeout <= 0 {
+		timeout = DefaultRolloutTimeout
+	}
+	return &RolloutWaiter{
+		clientSet: clientSet,
+		timeout:   timeout,
+		deadline:  time.Now().Add(timeout),
+		done:      make(map[string]bool),
+	}
+}
+
+// WaitFor waits for the rollouts of the Deployments one after another
+func (w *RolloutWaiter) WaitFor(names ...string) error {
+	t := terminal.NewTerminalPrint()
+	for _, name := range names {
+		if w.done[name] {
+			continue
+		}
+		for {
+			deployment, err := w.clientSet.AppsV1().Deployments(KubeConfig.Namespace).Get(name, metaV1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("Rollout Of %s Failed: %s", name, err)
+			}
+			complete, message, err := DeploymentRolloutStatus(deployment)
+			if err != nil {
+				t.LineEnd()
+				return fmt.Errorf("Rollout Of %s Failed: %s", name, err)
+			}
+			if complete {
+				t.PrintSuccessOneLine("%s Rolled Out", name)
+				t.LineEnd()
+				w.done[name] = true
+				break
+			}
+			t.PrintInfoOneLine("Waiting %s: %s", name, message)
+			remaining := time.Until(w.deadline)
+			if remaining <= 0 {
+				t.LineEnd()
+				return fmt.Errorf("Rollout Of %s Timed Out After %s: %s", name, w.timeout, message)
+			}
+			if remaining > rolloutPollInterval {
+				remaining = rolloutPollInterval
+			}
+			time.Sleep(remaining)
+		}
+	}
+	return nil
+}
+
+// DeploymentRolloutStatus reports whether the latest spec of the Deployment is rolled out,
+// with the progress message while it is not
+func DeploymentRolloutStatus(deployment *appsV1.Deployment) (bool, string, error) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false, "waiting for the new spec to be observed", nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsV1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, "", fmt.Errorf("progress deadline exceeded: %s", condition.Message)
+		}
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.UpdatedReplicas < replicas {
+		return false, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas), nil
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return false, fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas), nil
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return false, fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas), nil
+	}
+	if status.ReadyReplicas < replicas {
+		return false, fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, replicas), nil
+	}
+	return true, "", nil
+}
+
+// deploymentNames returns the names of the Deployments of the objects, in order
+func deploymentNames(objectList []runtime.Object) []string {
+	var names []string
+	for _, item := range objectList {
+		if deployment, ok := item.(*appsV1.Deployment); ok {
+			names = append(names, deployment.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,320 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"strings"
+	"time"
+)
+
+type UninstallOptions struct {
+	ReleaseName string
+	KeepData    bool
+	KeepCRDs    bool
+	Timeout     time.Duration
+}
+
+// newObjectClient returns the dynamic client of the resource a recorded object belongs to
+func newObjectClient(dynamicClient dynamic.Interface, mapper meta.RESTMapper, ref util.ObjectReference) (dynamic.ResourceInterface, error) {
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the version recorded at install may no longer be served, any version reaches the object
+		mapping, err = mapper.RESTMapping(gvk.GroupKind())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return dynamicClient.Resource(mapping.Resource).Namespace(ref.Namespace), nil
+	}
+	return dynamicClient.Resource(mapping.Resource), nil
+}
+
+func UninstallFuncEasyResources(options *UninstallOptions) error {
+	t := terminal.NewTerminalPrint()
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		return err
+	}
+	dynamicClient, mapper, err := NewK8sDynamicClient()
+	if err != nil {
+		return err
+	}
+	record, err := GetLatestReleaseRecord(clientSet, options.ReleaseName)
+	if err != nil {
+		return err
+	}
+	var objects []util.ObjectReference
+	if record != nil {
+		// the record lists the objects in the order install created them
+		objects = record.Objects
+	} else {
+		t.PrintWarnOneLine("No Release Record Found: %s, Looking Up Objects By Label", options.ReleaseName)
+		t.LineEnd()
+		objects, err = listReleaseObjects(clientSet, dynamicClient, options.ReleaseName)
+		if err != nil {
+			return err
+		}
+		objects = util.SortObjectReferences(objects)
+	}
+	if len(objects) == 0 {
+		t.PrintWarnOneLine("Not Install")
+		t.LineEnd()
+		return nil
+	}
+	// delete what depends on an object before the object
+	objects = util.ReverseObjectReferences(objects)
+
+	var localPaths []string
+	var nodeVolumes []*coreV1.PersistentVolume
+	var deleteList []util.ObjectReference
+	for _, ref := range objects {
+		switch ref.Kind {
+		case "PersistentVolumeClaim":
+			if options.KeepData {
+				continue
+			}
+		case "PersistentVolume":
+			if options.KeepData {
+				continue
+			}
+			pv, err := clientSet.CoreV1().PersistentVolumes().Get(ref.Name, metaV1.GetOptions{})
+			if err == nil && pv.Spec.HostPath != nil {
+				localPaths = append(localPaths, pv.Spec.HostPath.Path)
+			}
+			if err == nil && pv.Spec.Local != nil {
+				nodeVolumes = append(nodeVolumes, pv)
+			}
+		case "CustomResourceDefinition":
+			if options.KeepCRDs {
+				warnCustomResources(dynamicClient, mapper, ref, "still exist")
+				continue
+			}
+			others, err := otherReleaseNames(clientSet, options.ReleaseName)
+			if err != nil {
+				return err
+			}
+			if len(others) > 0 {
+				t.PrintWarnOneLine("Keep CRD: %s, Still Used By Releases %v", ref.Name, others)
+				t.LineEnd()
+				warnCustomResources(dynamicClient, mapper, ref, "still exist")
+				continue
+			}
+			warnCustomResources(dynamicClient, mapper, ref, "will be deleted with the CRD")
+		}
+		deleteList = append(deleteList, ref)
+	}
+
+	propagation := metaV1.DeletePropagationForeground
+	var waitList []dynamic.ResourceInterface
+	var waitNames []string
+	for _, ref := range deleteList {
+		c, err := newObjectClient(dynamicClient, mapper, ref)
+		if err != nil {
+			t.PrintWarnOneLine("Skip %s: %s, %s", ref.Kind, ref.Name, err)
+			t.LineEnd()
+			continue
+		}
+		t.PrintInfoOneLine("Deleting %s: %s", ref.Kind, ref.Name)
+		err = c.Delete(ref.Name, &metaV1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			t.PrintWarnOneLine("%s Not Found: %s", ref.Kind, ref.Name)
+			t.LineEnd()
+			continue
+		}
+		t.PrintSuccessOneLine("%s: %s Deleted", ref.Kind, ref.Name)
+		t.LineEnd()
+		waitList = append(waitList, c)
+		waitNames = append(waitNames, ref.Name)
+	}
+
+	done := make(chan bool)
+	t.PrintLoadingOneLine(done, "Waiting Deletion Complete")
+	deadline := time.After(options.Timeout)
+	for index, c := range waitList {
+		for {
+			_, err := c.Get(waitNames[index], metaV1.GetOptions{})
+			if errors.IsNotFound(err) {
+				break
+			}
+			if err != nil {
+				done <- true
+				return err
+			}
+			select {
+			case <-deadline:
+				done <- true
+				return fmt.Errorf("Timeout Waiting Deletion: %s", waitNames[index])
+			case <-time.After(2 * time.Second):
+			}
+		}
+	}
+	done <- true
+	t.PrintSuccessOneLine("Deletion Complete")
+	t.LineEnd()
+
+	for _, dirPath := range localPaths {
+		t.PrintInfoOneLine("Removing Local Data: %s", dirPath)
+		err := os.RemoveAll(dirPath)
+		if err != nil {
+			t.PrintWarnOneLine("Remove Local Data Failed: %s", err)
+			t.LineEnd()
+			continue
+		}
+		t.PrintSuccessOneLine("Local Data: %s Removed", dirPath)
+		t.LineEnd()
+	}
+	if len(nodeVolumes) > 0 {
+		RemoveLocalVolumes(clientSet, nodeVolumes, options.ReleaseName, record, options.Timeout)
+	}
+
+	err = clientSet.CoreV1().Secrets(KubeConfig.Namespace).DeleteCollection(&metaV1.DeleteOptions{}, metaV1.ListOptions{
+		LabelSelector: releaseRecordSelector(options.ReleaseName),
+	})
+	if err != nil {
+		return err
+	}
+	t.PrintSuccessOneLine("Release: %s Uninstalled", options.ReleaseName)
+	t.LineEnd()
+	return nil
+}
+
+// listReleaseObjects finds the objects of a release installed without a release record. It lists
+// every resource the server serves by the instance label, the objects a controller owns, such as
+// the pods of a Deployment, are left to their owner.
+func listReleaseObjects(clientSet *kubernetes.Clientset, dynamicClient dynamic.Interface, releaseName string) ([]util.ObjectReference, error) {
+	t := terminal.NewTerminalPrint()
+	resourceLists, err := clientSet.Discovery().ServerPreferredResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, err
+		}
+		t.PrintWarnOneLine("Some API Groups Are Not Listed: %s", err)
+		t.LineEnd()
+	}
+	listOptions := metaV1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{util.InstanceLabel: releaseName}).String(),
+	}
+	var objects []util.ObjectReference
+	// a kind served by several groups lists the same objects more than once
+	listed := make(map[types.UID]bool)
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			verbs := sets.NewString(resource.Verbs...)
+			if strings.Contains(resource.Name, "/") || !verbs.HasAll("list", "delete") || resource.Kind == "Endpoints" {
+				// the endpoints copy the labels of their service and go with it
+				continue
+			}
+			namespace := ""
+			if resource.Namespaced {
+				namespace = KubeConfig.Namespace
+			}
+			list, err := dynamicClient.Resource(gv.WithResource(resource.Name)).Namespace(namespace).List(listOptions)
+			if err != nil {
+				return nil, fmt.Errorf("List %s Failed: %s", resource.Kind, err)
+			}
+			for _, item := range list.Items {
+				if metaV1.GetControllerOf(&item) != nil || listed[item.GetUID()] {
+					continue
+				}
+				listed[item.GetUID()] = true
+				objects = append(objects, util.ObjectReference{
+					APIVersion: gv.String(),
+					Kind:       resource.Kind,
+					Namespace:  namespace,
+					Name:       item.GetName(),
+				})
+			}
+		}
+	}
+	return objects, nil
+}
+
+// otherReleaseNames lists the releases recorded in any namespace except this one
+func otherReleaseNames(clientSet *kubernetes.Clientset, releaseName string) ([]string, error) {
+	secrets, err := clientSet.CoreV1().Secrets(metaV1.NamespaceAll).List(metaV1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{"owner": "funceasy-cli"}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool)
+	var others []string
+	for _, secret := range secrets.Items {
+		if secret.Type != ReleaseRecordType {
+			continue
+		}
+		name := secret.Namespace + "/" + secret.Labels["name"]
+		if name == KubeConfig.Namespace+"/"+releaseName || exists[name] {
+			continue
+		}
+		exists[name] = true
+		others = append(others, name)
+	}
+	return others, nil
+}
+
+// warnCustomResources warns about the custom resources of a CRD left in the cluster
+func warnCustomResources(dynamicClient dynamic.Interface, mapper meta.RESTMapper, ref util.ObjectReference, state string) {
+	t := terminal.NewTerminalPrint()
+	c, err := newObjectClient(dynamicClient, mapper, ref)
+	if err != nil {
+		return
+	}
+	crd, err := c.Get(ref.Name, metaV1.GetOptions{})
+	if err != nil {
+		return
+	}
+	kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	count, err := countCustomResources(dynamicClient, crd)
+	if err != nil || count == 0 {
+		return
+	}
+	t.PrintWarnOneLine("%d %s Resources %s", count, kind, state)
+	t.LineEnd()
+}
+
+// countCustomResources counts the custom resources of a v1 or v1beta1 CRD in every namespace
+func countCustomResources(dynamicClient dynamic.Interface, crd *unstructured.Unstructured) (int, error) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+	version, _, _ := unstructured.NestedString(crd.Object, "spec", "version")
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, item := range versions {
+		if v, ok := item.(map[string]interface{}); ok && v["served"] == true {
+			version, _ = v["name"].(string)
+			break
+		}
+	}
+	list, err := dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    group,
+		Version:  version,
+		Resource: plural,
+	}).List(metaV1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return len(list.Items), nil
+}
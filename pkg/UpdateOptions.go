@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"github.com/spf13/pflag"
+)
+
+// UpdateFlags are the flags choosing the manifest and shaping the objects of an update,
+// diff takes the same ones so it compares the update the flags describe
+func UpdateFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("update", pflag.ExitOnError)
+	flags.StringP("file", "f", "", "the yaml file path to update to")
+	flags.AddFlagSet(release.VerifyFlags())
+	flags.String("bundle", "", "the offline bundle to update to, see bundle create")
+	flags.StringArray("values", []string{}, "a values file patching the manifest objects, can be repeated")
+	flags.StringArray("set", []string{}, "override a manifest field: kind.name.path=value, can be repeated")
+	flags.Bool("reset-values", false, "drop the values recorded by the previous install or update")
+	flags.String("image-registry", "", "rewrite the images to this mirror registry")
+	flags.StringArray("image-pull-secret", []string{}, "a Secret to pull the images with, can be repeated")
+	flags.String("expose", "", "expose the website, API and gateway: nodeport, loadbalancer or ingress, the installed mode is kept when unset")
+	flags.String("host", "", "the host the Ingress routes, its api. and gateway. subdomains included, or the address the endpoints use")
+	flags.String("profile", "", "adjust the replicas, resources, key size and exposure: dev, minimal, production or a profile of the config file, the installed one is kept when unset")
+	flags.String("external-database", "", "use this MySQL host:port instead of the bundled funceasy-mysql, the installed one is kept when unset")
+	flags.String("db-user", "", "the user of the external database")
+	flags.String("db-password-from-secret", "", "the Secret holding the external database password: name[:key], the key defaults to password")
+	return flags
+}
+
+// GetUpdateOptions reads the flags UpdateFlags adds into the options of an update of releaseName,
+// the storage and the settings left unset are taken from the installed revision
+func GetUpdateOptions(flags *pflag.FlagSet, releaseName string, values util.Values) (*ReleaseOptions, error) {
+	sets, err := flags.GetStringArray("set")
+	if err != nil {
+		return nil, err
+	}
+	resetValues, err := flags.GetBool("reset-values")
+	if err != nil {
+		return nil, err
+	}
+	imageRegistry, err := flags.GetString("image-registry")
+	if err != nil {
+		return nil, err
+	}
+	imagePullSecrets, err := flags.GetStringArray("image-pull-secret")
+	if err != nil {
+		return nil, err
+	}
+	expose, err := flags.GetString("expose")
+	if err != nil {
+		return nil, err
+	}
+	host, err := flags.GetString("host")
+	if err != nil {
+		return nil, err
+	}
+	profile, err := flags.GetString("profile")
+	if err != nil {
+		return nil, err
+	}
+	externalDatabase, err := flags.GetString("external-database")
+	if err != nil {
+		return nil, err
+	}
+	dbUser, err := flags.GetString("db-user")
+	if err != nil {
+		return nil, err
+	}
+	dbPasswordFrom, err := flags.GetString("db-password-from-secret")
+	if err != nil {
+		return nil, err
+	}
+	var database *util.ExternalDatabase
+	if externalDatabase != "" {
+		database, err = util.ParseExternalDatabase(externalDatabase, dbUser, dbPasswordFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ReleaseOptions{
+		ReleaseName:      releaseName,
+		Flags:            util.ChangedFlags(flags),
+		Values:           values,
+		Sets:             sets,
+		ResetValues:      resetValues,
+		ImageRegistry:    imageRegistry,
+		ImagePullSecrets: imagePullSecrets,
+		Expose:           expose,
+		Host:             host,
+		Database:         database,
+		Profile:          profile,
+	}, nil
+}
@@ -0,0 +1,300 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util"
+	"github.com/funceasy/funceasy-cli/pkg/util/release"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	coreV1 "k8s.io/api/core/v1"
+	storageV1 "k8s.io/api/storage/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"text/tabwriter"
+)
+
+// WizardFlags are the install flags InstallWizard answers
+var WizardFlags = []string{
+	"storage-class",
+	"local",
+	"node",
+	"profile",
+	"expose",
+	"host",
+	"external-database",
+	"db-user",
+	"db-password-from-secret",
+}
+
+// WizardVersionKey is the answer holding the release version, as in the install section of a values file
+const WizardVersionKey = "version"
+
+const (
+	wizardLocalStorage   = "local directory"
+	wizardOtherClass     = "another StorageClass"
+	wizardThisMachine    = "this machine (hostPath)"
+	wizardOtherNode      = "another node"
+	wizardNone           = "none"
+	wizardBundled        = "bundled funceasy-mysql"
+	wizardExternal       = "external MySQL"
+	wizardReleaseChoices = 10
+)
+
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// InstallWizard asks for the version when askVersion, then the storage, profile, exposure and
+// database of an install. current holds the values of WizardFlags the questions default to, the
+// answers are returned by flag name with every WizardFlags name set
+func InstallWizard(current map[string]string, askVersion bool) (map[string]string, error) {
+	t := terminal.NewTerminalPrint()
+	answers := make(map[string]string)
+	for _, name := range WizardFlags {
+		answers[name] = current[name]
+	}
+	if askVersion {
+		var names []string
+		for _, r := range release.GetRelease() {
+			names = append(names, r.Name)
+			if len(names) == wizardReleaseChoices {
+				break
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("No Release Found")
+		}
+		version, err := t.Select("FuncEasy version", names, names[0])
+		if err != nil {
+			return nil, err
+		}
+		answers[WizardVersionKey] = version
+	}
+	clientSet, _, err := NewK8sClientSet()
+	if err != nil {
+		// a client dry run needs no cluster, ask without the discovered choices
+		t.PrintWarnOneLine("Connect Cluster Failed: %s", err)
+		t.LineEnd()
+		clientSet = nil
+	}
+	err = askStorage(t, clientSet, answers)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := t.Select("Profile", append([]string{wizardNone}, ProfileNames()...), orNone(answers["profile"]))
+	if err != nil {
+		return nil, err
+	}
+	answers["profile"] = fromNone(profile)
+	err = askExpose(t, answers)
+	if err != nil {
+		return nil, err
+	}
+	err = askDatabase(t, answers)
+	if err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return wizardNone
+	}
+	return value
+}
+
+func fromNone(value string) string {
+	if value == wizardNone {
+		return ""
+	}
+	return value
+}
+
+// askStorage offers the StorageClasses of the cluster and a local directory, on a node or this machine
+func askStorage(t *terminal.Terminal, clientSet *kubernetes.Clientset, answers map[string]string) error {
+	var choices []string
+	defaultChoice := wizardLocalStorage
+	classes, err := listStorageClasses(clientSet)
+	if err != nil {
+		t.PrintWarnOneLine("List StorageClasses Failed: %s", err)
+		t.LineEnd()
+	} else {
+		for _, class := range classes {
+			choices = append(choices, class.Name)
+			if class.Annotations[defaultStorageClassAnnotation] == "true" && answers["storage-class"] == "" {
+				defaultChoice = class.Name
+			}
+		}
+	}
+	if answers["storage-class"] != "" {
+		defaultChoice = answers["storage-class"]
+	}
+	choices = append(choices, wizardOtherClass, wizardLocalStorage)
+	storage, err := t.Select("Storage of the PVCs", choices, defaultChoice)
+	if err != nil {
+		return err
+	}
+	if storage == wizardOtherClass {
+		storage, err = t.PromptRequired("StorageClass name", answers["storage-class"])
+		if err != nil {
+			return err
+		}
+	}
+	if storage != wizardLocalStorage {
+		answers["storage-class"] = storage
+		answers["local"] = ""
+		answers["node"] = ""
+		return nil
+	}
+	answers["storage-class"] = ""
+	var nodes []string
+	if clientSet != nil {
+		nodes, err = readyNodes(clientSet)
+	}
+	if err != nil {
+		t.PrintWarnOneLine("List Nodes Failed: %s", err)
+		t.LineEnd()
+	}
+	defaultNode := wizardThisMachine
+	if answers["node"] != "" {
+		defaultNode = answers["node"]
+	}
+	node, err := t.Select("Node of the local volumes", append(nodes, wizardOtherNode, wizardThisMachine), defaultNode)
+	if err != nil {
+		return err
+	}
+	if node == wizardOtherNode {
+		node, err = t.PromptRequired("Node hostname label", answers["node"])
+		if err != nil {
+			return err
+		}
+	}
+	if node == wizardThisMachine {
+		node = ""
+	}
+	answers["node"] = node
+	local, err := t.PromptRequired("Local directory", answers["local"])
+	if err != nil {
+		return err
+	}
+	answers["local"] = local
+	return nil
+}
+
+func listStorageClasses(clientSet *kubernetes.Clientset) ([]storageV1.StorageClass, error) {
+	if clientSet == nil {
+		return nil, nil
+	}
+	classes, err := clientSet.StorageV1().StorageClasses().List(metaV1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return classes.Items, nil
+}
+
+// readyNodes lists the hostname labels of the ready nodes, the local volumes select them by it
+func readyNodes(clientSet *kubernetes.Clientset) ([]string, error) {
+	nodes, err := clientSet.CoreV1().Nodes().List(metaV1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == coreV1.NodeReady && condition.Status == coreV1.ConditionTrue && node.Labels[coreV1.LabelHostname] != "" {
+				names = append(names, node.Labels[coreV1.LabelHostname])
+			}
+		}
+	}
+	return names, nil
+}
+
+func askExpose(t *terminal.Terminal, answers map[string]string) error {
+	choices := []string{wizardNone, util.ExposeNodePort, util.ExposeLoadBalancer, util.ExposeIngress}
+	expose, err := t.Select("Expose the website, API and gateway", choices, orNone(answers["expose"]))
+	if err != nil {
+		return err
+	}
+	answers["expose"] = fromNone(expose)
+	switch answers["expose"] {
+	case util.ExposeIngress:
+		answers["host"], err = t.PromptRequired("Ingress host, api. and gateway. subdomains included", answers["host"])
+	case util.ExposeNodePort, util.ExposeLoadBalancer:
+		answers["host"], err = t.Prompt("Address of the endpoints, empty to discover it", answers["host"])
+	default:
+		answers["host"] = ""
+	}
+	return err
+}
+
+func askDatabase(t *terminal.Terminal, answers map[string]string) error {
+	defaultChoice := wizardBundled
+	if answers["external-database"] != "" {
+		defaultChoice = wizardExternal
+	}
+	database, err := t.Select("Database", []string{wizardBundled, wizardExternal}, defaultChoice)
+	if err != nil {
+		return err
+	}
+	if database == wizardBundled {
+		answers["external-database"] = ""
+		answers["db-user"] = ""
+		answers["db-password-from-secret"] = ""
+		return nil
+	}
+	questions := []struct {
+		name     string
+		question string
+	}{
+		{"external-database", "MySQL host:port"},
+		{"db-user", "MySQL user"},
+		{"db-password-from-secret", "Secret holding the password, name[:key]"},
+	}
+	for _, q := range questions {
+		answers[q.name], err = t.PromptRequired(q.question, answers[q.name])
+		if err != nil {
+			return err
+		}
+	}
+	_, err = util.ParseExternalDatabase(answers["external-database"], answers["db-user"], answers["db-password-from-secret"])
+	return err
+}
+
+// PrintInstallSummary prints the settings of an install and the objects it creates
+func PrintInstallSummary(fileByte []byte, options *ReleaseOptions) error {
+	objectList, err := PrepareFuncEasyResources(fileByte, options)
+	if err != nil {
+		return err
+	}
+	storage := "StorageClass " + options.PathOrClass
+	if options.PVType == "Local" {
+		storage = "local directory " + options.PathOrClass + " on this machine"
+		if options.Node != "" {
+			storage = "local directory " + options.PathOrClass + " on node " + options.Node
+		}
+	}
+	expose := options.Expose
+	if profile, err := GetProfile(options.Profile); err == nil && profile != nil && expose == "" {
+		expose = profile.Expose
+	}
+	database := util.BundledDatabase
+	if options.Database != nil {
+		database = options.Database.Host + ":" + options.Database.Port
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Release:\t%s\n", options.ReleaseName)
+	_, _ = fmt.Fprintf(w, "Namespace:\t%s\n", KubeConfig.Namespace)
+	_, _ = fmt.Fprintf(w, "Version:\t%s\n", GetManifestVersion(objectList, options.ReleaseName))
+	_, _ = fmt.Fprintf(w, "Storage:\t%s\n", storage)
+	_, _ = fmt.Fprintf(w, "Profile:\t%s\n", orNone(options.Profile))
+	_, _ = fmt.Fprintf(w, "Expose:\t%s\n", orNone(expose))
+	_, _ = fmt.Fprintf(w, "Database:\t%s\n", database)
+	_ = w.Flush()
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KIND\tNAME\tNAMESPACE")
+	for _, item := range objectList {
+		ref := util.GetObjectReference(item)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", ref.Kind, ref.Name, ref.Namespace)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+var crdScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(apiextensions.AddToScheme(crdScheme))
+	utilruntime.Must(apiextensionsV1.AddToScheme(crdScheme))
+	utilruntime.Must(v1beta1.AddToScheme(crdScheme))
+}
+
+// ConvertCRDToV1 converts a v1beta1 CustomResourceDefinition the way the API server does.
+// The versions left without a schema, and every schema of a CRD that kept unknown fields,
+// accept unknown fields so the custom resources are not pruned.
+func ConvertCRDToV1(crd *v1beta1.CustomResourceDefinition) (*apiextensionsV1.CustomResourceDefinition, error) {
+	defaulted := crd.DeepCopy()
+	crdScheme.Default(defaulted)
+	preserveUnknownFields := defaulted.Spec.PreserveUnknownFields != nil && *defaulted.Spec.PreserveUnknownFields
+	internal := &apiextensions.CustomResourceDefinition{}
+	err := crdScheme.Convert(defaulted, internal, nil)
+	if err != nil {
+		return nil, err
+	}
+	converted := &apiextensionsV1.CustomResourceDefinition{}
+	err = crdScheme.Convert(internal, converted, nil)
+	if err != nil {
+		return nil, err
+	}
+	converted.APIVersion = apiextensionsV1.SchemeGroupVersion.String()
+	converted.Kind = "CustomResourceDefinition"
+	converted.Status = apiextensionsV1.CustomResourceDefinitionStatus{}
+	converted.Spec.PreserveUnknownFields = false
+	preserve := true
+	for i := range converted.Spec.Versions {
+		version := &converted.Spec.Versions[i]
+		if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+			version.Schema = &apiextensionsV1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsV1.JSONSchemaProps{Type: "object"},
+			}
+			version.Schema.OpenAPIV3Schema.XPreserveUnknownFields = &preserve
+		} else if preserveUnknownFields {
+			version.Schema.OpenAPIV3Schema.XPreserveUnknownFields = &preserve
+		}
+		if version.Schema.OpenAPIV3Schema.Type == "" {
+			version.Schema.OpenAPIV3Schema.Type = "object"
+		}
+	}
+	return converted, nil
+}
@@ -0,0 +1,136 @@
+package util
+
+import (
+	"fmt"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// BundledDatabase names the MySQL objects of the manifest, funceasy-mysql and funceasy-mysql-*
+const BundledDatabase string = "funceasy-mysql"
+
+// DatabaseSecretName is the Secret the services read the database connection from,
+// with the keys host, port, user and password
+const DatabaseSecretName string = "funceasy-database"
+
+// DefaultDatabasePasswordKey is the key of the password Secret when none is given
+const DefaultDatabasePasswordKey string = "password"
+
+// ExternalDatabase is a MySQL server outside the release the services use instead of funceasy-mysql
+type ExternalDatabase struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+	User string `json:"user"`
+	// PasswordSecret and PasswordKey point at the password, in a Secret of the release namespace
+	PasswordSecret string `json:"passwordSecret"`
+	PasswordKey    string `json:"passwordKey"`
+}
+
+// ParseExternalDatabase reads the host:port address and the secret[:key] password reference
+func ParseExternalDatabase(address string, user string, passwordFrom string) (*ExternalDatabase, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid External Database %s: %s", address, err)
+	}
+	if user == "" || passwordFrom == "" {
+		return nil, fmt.Errorf("External Database Needs A User And A Password Secret")
+	}
+	database := &ExternalDatabase{
+		Host:           host,
+		Port:           port,
+		User:           user,
+		PasswordSecret: passwordFrom,
+		PasswordKey:    DefaultDatabasePasswordKey,
+	}
+	if parts := strings.SplitN(passwordFrom, ":", 2); len(parts) == 2 {
+		database.PasswordSecret = parts[0]
+		database.PasswordKey = parts[1]
+	}
+	return database, nil
+}
+
+func isBundledDatabase(name string) bool {
+	return name == BundledDatabase || strings.HasPrefix(name, BundledDatabase+"-")
+}
+
+// SetExternalDatabase drops the bundled MySQL objects and the dependencies on them, points
+// the container env at the external server and sets the connection Secret, without the password
+// the install reads from the password Secret
+func SetExternalDatabase(objectList []runtime.Object, database *ExternalDatabase) []runtime.Object {
+	if database == nil {
+		return objectList
+	}
+	hostPattern := regexp.MustCompile(fmt.Sprintf(`(^|[^a-zA-Z0-9.\-])%s(:[0-9]+)?($|[^a-zA-Z0-9\-])`, regexp.QuoteMeta(BundledDatabase)))
+	rewriteHost := func(value string) string {
+		return hostPattern.ReplaceAllStringFunc(value, func(match string) string {
+			groups := hostPattern.FindStringSubmatch(match)
+			host := database.Host
+			if groups[2] != "" {
+				host = net.JoinHostPort(database.Host, database.Port)
+			}
+			return groups[1] + host + groups[3]
+		})
+	}
+	var kept []runtime.Object
+	var secret *coreV1.Secret
+	for _, item := range objectList {
+		accessor, err := meta.Accessor(item)
+		if err == nil && isBundledDatabase(accessor.GetName()) {
+			continue
+		}
+		if s, ok := item.(*coreV1.Secret); ok && s.Name == DatabaseSecretName {
+			secret = s
+		}
+		if deployment, ok := item.(*appsV1.Deployment); ok {
+			var dependencies []string
+			for _, name := range DeploymentDependencies(deployment) {
+				if !isBundledDatabase(name) {
+					dependencies = append(dependencies, name)
+				}
+			}
+			if _, ok := deployment.Annotations[DependsOnAnnotation]; ok {
+				deployment.Annotations[DependsOnAnnotation] = strings.Join(dependencies, ",")
+			}
+		}
+		if template, _ := PodTemplate(item); template != nil {
+			containers := template.Spec.Containers
+			for i := range containers {
+				for j := range containers[i].Env {
+					containers[i].Env[j].Value = rewriteHost(containers[i].Env[j].Value)
+				}
+			}
+		}
+		if configMap, ok := item.(*coreV1.ConfigMap); ok {
+			for key, value := range configMap.Data {
+				configMap.Data[key] = rewriteHost(value)
+			}
+		}
+		kept = append(kept, item)
+	}
+	if secret == nil {
+		secret = &coreV1.Secret{
+			TypeMeta: metaV1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Secret",
+			},
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: DatabaseSecretName,
+			},
+		}
+		kept = append(kept, secret)
+	}
+	secret.Type = coreV1.SecretTypeOpaque
+	secret.Data = map[string][]byte{
+		"host": []byte(database.Host),
+		"port": []byte(database.Port),
+		"user": []byte(database.User),
+	}
+	secret.StringData = nil
+	return kept
+}
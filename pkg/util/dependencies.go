@@ -0,0 +1,80 @@
+package util
+
+import (
+	"fmt"
+	appsV1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"strings"
+)
+
+// DependsOnAnnotation lists the Deployments, comma separated, whose rollout a Deployment waits for
+const DependsOnAnnotation string = "funceasy.io/depends-on"
+
+// DeploymentDependencies returns the Deployments the annotation of deployment lists
+func DeploymentDependencies(deployment *appsV1.Deployment) []string {
+	var dependencies []string
+	for _, name := range strings.Split(deployment.Annotations[DependsOnAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			dependencies = append(dependencies, name)
+		}
+	}
+	return dependencies
+}
+
+// OrderDeployments moves every Deployment after its dependencies, keeping the manifest order otherwise.
+// The other objects keep their positions.
+func OrderDeployments(objectList []runtime.Object) ([]runtime.Object, error) {
+	var slots []int
+	deployments := make(map[string]*appsV1.Deployment)
+	var names []string
+	for index, item := range objectList {
+		if deployment, ok := item.(*appsV1.Deployment); ok {
+			slots = append(slots, index)
+			deployments[deployment.Name] = deployment
+			names = append(names, deployment.Name)
+		}
+	}
+	for _, name := range names {
+		for _, dependency := range DeploymentDependencies(deployments[name]) {
+			if _, ok := deployments[dependency]; !ok {
+				return nil, fmt.Errorf("Deployment %s Depends On Unknown Deployment: %s", name, dependency)
+			}
+		}
+	}
+	var ordered []runtime.Object
+	placed := make(map[string]bool)
+	for len(ordered) < len(names) {
+		progress := false
+		for _, name := range names {
+			if placed[name] {
+				continue
+			}
+			ready := true
+			for _, dependency := range DeploymentDependencies(deployments[name]) {
+				if !placed[dependency] {
+					ready = false
+				}
+			}
+			if ready {
+				placed[name] = true
+				ordered = append(ordered, deployments[name])
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			var cycle []string
+			for _, name := range names {
+				if !placed[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, fmt.Errorf("Deployment Dependency Cycle: %s", strings.Join(cycle, ", "))
+		}
+	}
+	result := append([]runtime.Object{}, objectList...)
+	for i, slot := range slots {
+		result[slot] = ordered[i]
+	}
+	return result, nil
+}
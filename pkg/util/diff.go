@@ -0,0 +1,135 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines around the changes of a unified diff
+const DiffContext = 3
+
+type diffLine struct {
+	kind byte
+	text string
+	// from and to count the lines of each side before this one
+	from int
+	to   int
+}
+
+// diffLines walks the longest common subsequence of the lines, marking them ' ', '-' or '+'
+func diffLines(from []string, to []string) []diffLine {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	a := from[prefix : len(from)-suffix]
+	b := to[prefix : len(to)-suffix]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	for k := 0; k < prefix; k++ {
+		lines = append(lines, diffLine{kind: ' ', text: from[k], from: k, to: k})
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		line := diffLine{from: prefix + i, to: prefix + j}
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			line.kind, line.text = ' ', a[i]
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			line.kind, line.text = '-', a[i]
+			i++
+		default:
+			line.kind, line.text = '+', b[j]
+			j++
+		}
+		lines = append(lines, line)
+	}
+	for k := 0; k < suffix; k++ {
+		lines = append(lines, diffLine{kind: ' ', text: from[len(from)-suffix+k], from: len(from) - suffix + k, to: len(to) - suffix + k})
+	}
+	return lines
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// UnifiedDiff returns the unified diff of two texts, empty when they are equal
+func UnifiedDiff(fromName string, toName string, fromText string, toText string) []string {
+	if fromText == toText {
+		return nil
+	}
+	lines := diffLines(splitLines(fromText), splitLines(toText))
+	diff := []string{"--- " + fromName, "+++ " + toName}
+	i := 0
+	for i < len(lines) {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := i - DiffContext
+		if start < 0 {
+			start = 0
+		}
+		// join the changes at most two contexts apart into one hunk
+		end := i + 1
+		for j := i; j < len(lines) && j-end <= 2*DiffContext; j++ {
+			if lines[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		end += DiffContext
+		if end > len(lines) {
+			end = len(lines)
+		}
+		fromCount, toCount := 0, 0
+		var body []string
+		for _, line := range lines[start:end] {
+			if line.kind != '+' {
+				fromCount++
+			}
+			if line.kind != '-' {
+				toCount++
+			}
+			body = append(body, string(line.kind)+line.text)
+		}
+		header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(lines[start].from, fromCount), hunkRange(lines[start].to, toCount))
+		diff = append(diff, header)
+		diff = append(diff, body...)
+		i = end
+	}
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
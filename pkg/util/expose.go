@@ -0,0 +1,105 @@
+package util
+
+import (
+	"fmt"
+	coreV1 "k8s.io/api/core/v1"
+	networkingV1beta1 "k8s.io/api/networking/v1beta1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	ExposeNodePort     = "nodeport"
+	ExposeLoadBalancer = "loadbalancer"
+	ExposeIngress      = "ingress"
+)
+
+// ExposedService is a Service of the manifest users reach from outside the cluster
+type ExposedService struct {
+	Name string
+	// Subdomain of the --host the Ingress routes to the Service, empty for the host itself
+	Subdomain string
+}
+
+var ExposedServices = []ExposedService{
+	{Name: "funceasy-website"},
+	{Name: "funceasy-api", Subdomain: "api"},
+	{Name: "funceasy-gateway", Subdomain: "gateway"},
+}
+
+// ExposedHost returns the Ingress host of the Service
+func ExposedHost(service ExposedService, host string) string {
+	if service.Subdomain == "" {
+		return host
+	}
+	return service.Subdomain + "." + host
+}
+
+// ExposeObjects makes the exposed Services NodePort or LoadBalancer Services, or ClusterIP Services
+// behind a generated Ingress routing host and its subdomains. An empty mode keeps the manifest as is
+func ExposeObjects(objectList []runtime.Object, mode string, host string) ([]runtime.Object, error) {
+	var serviceType coreV1.ServiceType
+	switch mode {
+	case "":
+		return objectList, nil
+	case ExposeNodePort:
+		serviceType = coreV1.ServiceTypeNodePort
+	case ExposeLoadBalancer:
+		serviceType = coreV1.ServiceTypeLoadBalancer
+	case ExposeIngress:
+		if host == "" {
+			return nil, fmt.Errorf("Expose Mode %s Needs A Host", ExposeIngress)
+		}
+		serviceType = coreV1.ServiceTypeClusterIP
+	default:
+		return nil, fmt.Errorf("Unknown Expose Mode: %s, use %s, %s or %s", mode, ExposeNodePort, ExposeLoadBalancer, ExposeIngress)
+	}
+	var rules []networkingV1beta1.IngressRule
+	for _, exposed := range ExposedServices {
+		for _, item := range objectList {
+			service, ok := item.(*coreV1.Service)
+			if !ok || service.Name != exposed.Name || len(service.Spec.Ports) == 0 {
+				continue
+			}
+			service.Spec.Type = serviceType
+			if serviceType == coreV1.ServiceTypeClusterIP {
+				for i := range service.Spec.Ports {
+					service.Spec.Ports[i].NodePort = 0
+				}
+			}
+			rules = append(rules, networkingV1beta1.IngressRule{
+				Host: ExposedHost(exposed, host),
+				IngressRuleValue: networkingV1beta1.IngressRuleValue{
+					HTTP: &networkingV1beta1.HTTPIngressRuleValue{
+						Paths: []networkingV1beta1.HTTPIngressPath{
+							{
+								Path: "/",
+								Backend: networkingV1beta1.IngressBackend{
+									ServiceName: service.Name,
+									ServicePort: intstr.FromInt(int(service.Spec.Ports[0].Port)),
+								},
+							},
+						},
+					},
+				},
+			})
+		}
+	}
+	if mode != ExposeIngress || len(rules) == 0 {
+		return objectList, nil
+	}
+	ingress := &networkingV1beta1.Ingress{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: networkingV1beta1.SchemeGroupVersion.String(),
+			Kind:       "Ingress",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: "funceasy-ingress",
+		},
+		Spec: networkingV1beta1.IngressSpec{
+			Rules: rules,
+		},
+	}
+	return append(objectList, ingress), nil
+}
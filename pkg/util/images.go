@@ -0,0 +1,112 @@
+package util
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sort"
+	"strings"
+)
+
+// MirrorImage moves an image reference to registry, keeping the repository path and tag.
+// Official Docker Hub images get the library/ prefix the mirrors use.
+func MirrorImage(image string, registry string) string {
+	if registry == "" {
+		return image
+	}
+	repository := image
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		repository = parts[1]
+	} else if len(parts) == 1 {
+		repository = "library/" + image
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + repository
+}
+
+func podSpecs(objectList []runtime.Object) []*coreV1.PodSpec {
+	var specs []*coreV1.PodSpec
+	for _, item := range objectList {
+		if template, _ := PodTemplate(item); template != nil {
+			specs = append(specs, &template.Spec)
+		}
+	}
+	return specs
+}
+
+// SetObjectsImageRegistry rewrites every container and init container image of the workloads to the registry
+func SetObjectsImageRegistry(objectList []runtime.Object, registry string) {
+	if registry == "" {
+		return
+	}
+	for _, spec := range podSpecs(objectList) {
+		for i := range spec.InitContainers {
+			spec.InitContainers[i].Image = MirrorImage(spec.InitContainers[i].Image, registry)
+		}
+		for i := range spec.Containers {
+			spec.Containers[i].Image = MirrorImage(spec.Containers[i].Image, registry)
+		}
+	}
+}
+
+// SetObjectsImagePullSecrets attaches the pull secrets to the pods and to the ServiceAccounts,
+// so the pods the operator creates for the functions can pull from the registry too
+func SetObjectsImagePullSecrets(objectList []runtime.Object, secrets []string) {
+	if len(secrets) == 0 {
+		return
+	}
+	add := func(references []coreV1.LocalObjectReference) []coreV1.LocalObjectReference {
+		for _, secret := range secrets {
+			exists := false
+			for _, reference := range references {
+				if reference.Name == secret {
+					exists = true
+				}
+			}
+			if !exists {
+				references = append(references, coreV1.LocalObjectReference{Name: secret})
+			}
+		}
+		return references
+	}
+	for _, spec := range podSpecs(objectList) {
+		spec.ImagePullSecrets = add(spec.ImagePullSecrets)
+	}
+	for _, item := range objectList {
+		if sa, ok := item.(*coreV1.ServiceAccount); ok {
+			sa.ImagePullSecrets = add(sa.ImagePullSecrets)
+		}
+	}
+}
+
+// ListImages returns the sorted images the manifest objects reference
+func ListImages(objectList []runtime.Object) []string {
+	exists := make(map[string]bool)
+	var images []string
+	for _, spec := range podSpecs(objectList) {
+		containers := append(append([]coreV1.Container{}, spec.InitContainers...), spec.Containers...)
+		for _, container := range containers {
+			if !exists[container.Image] {
+				exists[container.Image] = true
+				images = append(images, container.Image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// MergeImages adds the extra images missing from images, keeping them sorted
+func MergeImages(images []string, extra ...string) []string {
+	exists := make(map[string]bool)
+	for _, image := range images {
+		exists[image] = true
+	}
+	for _, image := range extra {
+		if !exists[image] {
+			exists[image] = true
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
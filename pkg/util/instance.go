@@ -0,0 +1,266 @@
+package util
+
+import (
+	"fmt"
+	appsV1 "k8s.io/api/apps/v1"
+	autoscalingV1 "k8s.io/api/autoscaling/v1"
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingV1beta1 "k8s.io/api/networking/v1beta1"
+	policyV1beta1 "k8s.io/api/policy/v1beta1"
+	rbacV1 "k8s.io/api/rbac/v1"
+	apiextensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"regexp"
+	"strings"
+)
+
+const DefaultReleaseName string = "funceasy"
+
+const InstanceLabel string = "app.kubernetes.io/instance"
+
+// ReleaseObjectName returns the name an object of the manifest gets in the release.
+// The default release keeps the manifest names so existing installations stay untouched.
+func ReleaseObjectName(releaseName string, name string) string {
+	if releaseName == DefaultReleaseName || name == "" {
+		return name
+	}
+	return releaseName + "-" + name
+}
+
+// SetObjectsRelease prefixes the names of the manifest objects and every reference between
+// them with the release name, and labels the objects and their pods with the instance label.
+// The references to objects outside the manifest, such as the built-in ClusterRoles, the
+// default ServiceAccount or the Secrets of the user, keep their names
+func SetObjectsRelease(objectList []runtime.Object, releaseName string) {
+	rename := func(name string) string {
+		return ReleaseObjectName(releaseName, name)
+	}
+	var serviceNames []string
+	manifestNames := make(map[string]map[string]bool)
+	for _, item := range objectList {
+		if service, ok := item.(*coreV1.Service); ok {
+			serviceNames = append(serviceNames, service.Name)
+		}
+		ref := GetObjectReference(item)
+		if manifestNames[ref.Kind] == nil {
+			manifestNames[ref.Kind] = make(map[string]bool)
+		}
+		manifestNames[ref.Kind][ref.Name] = true
+	}
+	renameHosts := func(value string) string {
+		return renameServiceHosts(value, serviceNames, releaseName)
+	}
+	renameRef := func(kind string, name string) string {
+		if manifestNames[kind][name] {
+			return rename(name)
+		}
+		return name
+	}
+	renameService := func(name string) string {
+		return renameRef("Service", name)
+	}
+	for _, item := range objectList {
+		if template, selector := PodTemplate(item); template != nil {
+			if selector != nil {
+				renameAppLabel(selector.MatchLabels, rename)
+			}
+			setReleaseLabels(&template.Labels, releaseName, rename)
+			setPodSpecRelease(&template.Spec, rename, renameRef, renameHosts)
+		}
+		switch item.(type) {
+		case *v1beta1.CustomResourceDefinition, *apiextensionsV1.CustomResourceDefinition, *coreV1.Namespace:
+			// names are fixed by the API group or shared by the releases
+			continue
+		case *appsV1.Deployment:
+			deployment := item.(*appsV1.Deployment)
+			if dependencies := DeploymentDependencies(deployment); len(dependencies) > 0 {
+				for i := range dependencies {
+					dependencies[i] = renameRef("Deployment", dependencies[i])
+				}
+				deployment.Annotations[DependsOnAnnotation] = strings.Join(dependencies, ",")
+			}
+		case *coreV1.Service:
+			service := item.(*coreV1.Service)
+			renameAppLabel(service.Spec.Selector, rename)
+		case *coreV1.ConfigMap:
+			configMap := item.(*coreV1.ConfigMap)
+			for key, value := range configMap.Data {
+				configMap.Data[key] = renameHosts(value)
+			}
+		case *networkingV1beta1.Ingress:
+			ingress := item.(*networkingV1beta1.Ingress)
+			setIngressRelease(&ingress.Spec, renameService)
+		case *extensionsV1beta1.Ingress:
+			ingress := item.(*extensionsV1beta1.Ingress)
+			if ingress.Spec.Backend != nil {
+				ingress.Spec.Backend.ServiceName = renameService(ingress.Spec.Backend.ServiceName)
+			}
+			for i := range ingress.Spec.Rules {
+				if ingress.Spec.Rules[i].HTTP == nil {
+					continue
+				}
+				for j := range ingress.Spec.Rules[i].HTTP.Paths {
+					backend := &ingress.Spec.Rules[i].HTTP.Paths[j].Backend
+					backend.ServiceName = renameService(backend.ServiceName)
+				}
+			}
+		case *policyV1beta1.PodDisruptionBudget:
+			pdb := item.(*policyV1beta1.PodDisruptionBudget)
+			if pdb.Spec.Selector != nil {
+				renameAppLabel(pdb.Spec.Selector.MatchLabels, rename)
+			}
+		case *autoscalingV1.HorizontalPodAutoscaler:
+			hpa := item.(*autoscalingV1.HorizontalPodAutoscaler)
+			hpa.Spec.ScaleTargetRef.Name = renameRef(hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)
+		case *coreV1.PersistentVolumeClaim:
+			pvc := item.(*coreV1.PersistentVolumeClaim)
+			pvc.Spec.VolumeName = renameRef("PersistentVolume", pvc.Spec.VolumeName)
+		case *rbacV1.RoleBinding:
+			rb := item.(*rbacV1.RoleBinding)
+			rb.RoleRef.Name = renameRef(rb.RoleRef.Kind, rb.RoleRef.Name)
+			setSubjectsRelease(rb.Subjects, renameRef)
+		case *rbacV1.ClusterRoleBinding:
+			crb := item.(*rbacV1.ClusterRoleBinding)
+			crb.RoleRef.Name = renameRef(crb.RoleRef.Kind, crb.RoleRef.Name)
+			setSubjectsRelease(crb.Subjects, renameRef)
+		}
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		accessor.SetName(rename(accessor.GetName()))
+		objectLabels := accessor.GetLabels()
+		setReleaseLabels(&objectLabels, releaseName, rename)
+		accessor.SetLabels(objectLabels)
+	}
+}
+
+func setIngressRelease(spec *networkingV1beta1.IngressSpec, renameService func(string) string) {
+	if spec.Backend != nil {
+		spec.Backend.ServiceName = renameService(spec.Backend.ServiceName)
+	}
+	for i := range spec.Rules {
+		if spec.Rules[i].HTTP == nil {
+			continue
+		}
+		for j := range spec.Rules[i].HTTP.Paths {
+			backend := &spec.Rules[i].HTTP.Paths[j].Backend
+			backend.ServiceName = renameService(backend.ServiceName)
+		}
+	}
+}
+
+func setReleaseLabels(labels *map[string]string, releaseName string, rename func(string) string) {
+	if *labels == nil {
+		*labels = make(map[string]string)
+	}
+	renameAppLabel(*labels, rename)
+	(*labels)[InstanceLabel] = releaseName
+}
+
+func renameAppLabel(labels map[string]string, rename func(string) string) {
+	if app, ok := labels["app"]; ok {
+		labels["app"] = rename(app)
+	}
+}
+
+func setSubjectsRelease(subjects []rbacV1.Subject, renameRef func(string, string) string) {
+	for i := range subjects {
+		if subjects[i].Kind == rbacV1.ServiceAccountKind {
+			subjects[i].Name = renameRef(rbacV1.ServiceAccountKind, subjects[i].Name)
+		}
+	}
+}
+
+func setPodSpecRelease(podSpec *coreV1.PodSpec, rename func(string) string, renameRef func(string, string) string,
+	renameHosts func(string) string) {
+	podSpec.ServiceAccountName = renameRef("ServiceAccount", podSpec.ServiceAccountName)
+	if affinity := podSpec.Affinity; affinity != nil {
+		var terms []coreV1.PodAffinityTerm
+		if affinity.PodAffinity != nil {
+			terms = append(terms, affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+			for _, weighted := range affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				terms = append(terms, weighted.PodAffinityTerm)
+			}
+		}
+		if affinity.PodAntiAffinity != nil {
+			terms = append(terms, affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+			for _, weighted := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				terms = append(terms, weighted.PodAffinityTerm)
+			}
+		}
+		// the terms share their label selectors with the pod spec
+		for _, term := range terms {
+			if term.LabelSelector != nil {
+				renameAppLabel(term.LabelSelector.MatchLabels, rename)
+			}
+		}
+	}
+	for i := range podSpec.Volumes {
+		volume := &podSpec.Volumes[i]
+		if volume.ConfigMap != nil {
+			volume.ConfigMap.Name = renameRef("ConfigMap", volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			volume.Secret.SecretName = renameRef("Secret", volume.Secret.SecretName)
+		}
+		if volume.PersistentVolumeClaim != nil {
+			volume.PersistentVolumeClaim.ClaimName = renameRef("PersistentVolumeClaim", volume.PersistentVolumeClaim.ClaimName)
+		}
+		if volume.Projected != nil {
+			for j := range volume.Projected.Sources {
+				source := &volume.Projected.Sources[j]
+				if source.ConfigMap != nil {
+					source.ConfigMap.Name = renameRef("ConfigMap", source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					source.Secret.Name = renameRef("Secret", source.Secret.Name)
+				}
+			}
+		}
+	}
+	containers := [][]coreV1.Container{podSpec.InitContainers, podSpec.Containers}
+	for _, list := range containers {
+		for i := range list {
+			container := &list[i]
+			for j := range container.EnvFrom {
+				envFrom := &container.EnvFrom[j]
+				if envFrom.ConfigMapRef != nil {
+					envFrom.ConfigMapRef.Name = renameRef("ConfigMap", envFrom.ConfigMapRef.Name)
+				}
+				if envFrom.SecretRef != nil {
+					envFrom.SecretRef.Name = renameRef("Secret", envFrom.SecretRef.Name)
+				}
+			}
+			for j := range container.Env {
+				env := &container.Env[j]
+				env.Value = renameHosts(env.Value)
+				if env.ValueFrom == nil {
+					continue
+				}
+				if env.ValueFrom.ConfigMapKeyRef != nil {
+					env.ValueFrom.ConfigMapKeyRef.Name = renameRef("ConfigMap", env.ValueFrom.ConfigMapKeyRef.Name)
+				}
+				if env.ValueFrom.SecretKeyRef != nil {
+					env.ValueFrom.SecretKeyRef.Name = renameRef("Secret", env.ValueFrom.SecretKeyRef.Name)
+				}
+			}
+		}
+	}
+}
+
+// renameServiceHosts rewrites the service host names used in config values,
+// e.g. "funceasy-mysql:3306" or "http://funceasy-api.funceasy.svc"
+func renameServiceHosts(value string, serviceNames []string, releaseName string) string {
+	if releaseName == DefaultReleaseName {
+		return value
+	}
+	for _, name := range serviceNames {
+		r := regexp.MustCompile(fmt.Sprintf(`(^|[^a-zA-Z0-9.\-])%s($|[^a-zA-Z0-9\-])`, regexp.QuoteMeta(name)))
+		value = r.ReplaceAllString(value, "${1}"+ReleaseObjectName(releaseName, name)+"${2}")
+	}
+	return value
+}
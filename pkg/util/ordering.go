@@ -0,0 +1,117 @@
+package util
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sort"
+	"strconv"
+)
+
+// OrderWeightAnnotation moves an object before (negative) or after (positive) the objects
+// of the default weight 0, whatever its kind
+const OrderWeightAnnotation string = "funceasy.io/order-weight"
+
+// InstallOrder is the kind priority objects are created in, what others depend on first.
+// Kinds not listed are created last.
+var InstallOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"CustomResourceDefinition",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicaSet",
+	"Deployment",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"HorizontalPodAutoscaler",
+	"Ingress",
+}
+
+// KindPriority returns the position of the kind in InstallOrder
+func KindPriority(kind string) int {
+	for index, item := range InstallOrder {
+		if item == kind {
+			return index
+		}
+	}
+	return len(InstallOrder)
+}
+
+func orderWeight(obj runtime.Object) (int, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return 0, nil
+	}
+	value, ok := accessor.GetAnnotations()[OrderWeightAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s Of %s: %s", OrderWeightAnnotation, accessor.GetName(), value)
+	}
+	return weight, nil
+}
+
+// SortObjects orders the objects by order weight, then kind priority, then manifest order
+func SortObjects(objectList []runtime.Object) ([]runtime.Object, error) {
+	weights := make([]int, len(objectList))
+	priorities := make([]int, len(objectList))
+	indexes := make([]int, len(objectList))
+	for index, item := range objectList {
+		weight, err := orderWeight(item)
+		if err != nil {
+			return nil, err
+		}
+		weights[index] = weight
+		priorities[index] = KindPriority(GetObjectReference(item).Kind)
+		indexes[index] = index
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := indexes[i], indexes[j]
+		if weights[a] != weights[b] {
+			return weights[a] < weights[b]
+		}
+		return priorities[a] < priorities[b]
+	})
+	sorted := make([]runtime.Object, len(objectList))
+	for position, index := range indexes {
+		sorted[position] = objectList[index]
+	}
+	return sorted, nil
+}
+
+// SortObjectReferences orders the references by kind priority, keeping their order otherwise
+func SortObjectReferences(refs []ObjectReference) []ObjectReference {
+	sorted := append([]ObjectReference{}, refs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return KindPriority(sorted[i].Kind) < KindPriority(sorted[j].Kind)
+	})
+	return sorted
+}
+
+// ReverseObjectReferences returns the references in the opposite order
+func ReverseObjectReferences(refs []ObjectReference) []ObjectReference {
+	reversed := make([]ObjectReference, len(refs))
+	for index, ref := range refs {
+		reversed[len(refs)-1-index] = ref
+	}
+	return reversed
+}
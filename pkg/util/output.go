@@ -0,0 +1,89 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+const RedactedValue string = "REDACTED"
+
+// ToUnstructured converts a typed manifest object, filling apiVersion and kind
+// for objects built in code and dropping the empty fields a typed object always has
+func ToUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u = u.DeepCopy()
+		unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(u.Object, "status")
+		return u, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	if u.GetKind() == "" {
+		kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return nil, err
+		}
+		u.SetGroupVersionKind(kinds[0])
+	}
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+	return u, nil
+}
+
+// RedactSecrets hides the values of the Secrets data
+func RedactSecrets(objects []*unstructured.Unstructured) {
+	for _, obj := range objects {
+		if obj.GetKind() != "Secret" {
+			continue
+		}
+		for _, field := range []string{"data", "stringData"} {
+			data, found, err := unstructured.NestedMap(obj.Object, field)
+			if err != nil || !found {
+				continue
+			}
+			for key := range data {
+				data[key] = RedactedValue
+			}
+			_ = unstructured.SetNestedMap(obj.Object, data, field)
+		}
+	}
+}
+
+// PrintObjects writes the objects as a YAML stream or a JSON List
+func PrintObjects(w io.Writer, objects []*unstructured.Unstructured, output string) error {
+	switch output {
+	case "yaml":
+		for _, obj := range objects {
+			data, err := yaml.Marshal(obj.Object)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(w, "---\n%s", data)
+		}
+	case "json":
+		items := make([]interface{}, 0, len(objects))
+		for _, obj := range objects {
+			items = append(items, obj.Object)
+		}
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(w, string(data))
+	default:
+		return fmt.Errorf("Unsupported Output Format: %s", output)
+	}
+	return nil
+}
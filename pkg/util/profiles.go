@@ -0,0 +1,206 @@
+package util
+
+import (
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	policyV1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DefaultKeyBits is the size of the RSA keys generated for the Secrets labeled generatedBy: cli
+const DefaultKeyBits = 1024
+
+// Profile adjusts the manifest objects to an environment, the zero value of a field keeps the manifest
+type Profile struct {
+	// Base is the profile this one starts from
+	Base string `json:"base,omitempty"`
+	// Replicas of the Deployments and StatefulSets, funceasy-mysql and the ones mounting a PVC keep theirs
+	Replicas int32 `json:"replicas,omitempty"`
+	// Resources fill the requests and limits the containers leave unset
+	Resources coreV1.ResourceRequirements `json:"resources,omitempty"`
+	// AntiAffinity spreads the pods of a workload over the nodes
+	AntiAffinity *bool `json:"antiAffinity,omitempty"`
+	// PodDisruptionBudget keeps one pod of each replicated Deployment during node drains
+	PodDisruptionBudget *bool `json:"podDisruptionBudget,omitempty"`
+	// KeyBits is the size of the generated RSA keys
+	KeyBits int `json:"keyBits,omitempty"`
+	// Expose is the expose mode used when --expose is not given
+	Expose string `json:"expose,omitempty"`
+}
+
+var enabled = true
+
+// Profiles are the built-in profiles, the config file may override them or add its own
+var Profiles = map[string]Profile{
+	"dev": {
+		Replicas: 1,
+		KeyBits:  1024,
+		Expose:   ExposeNodePort,
+	},
+	"minimal": {
+		Replicas: 1,
+		Resources: coreV1.ResourceRequirements{
+			Requests: coreV1.ResourceList{
+				coreV1.ResourceCPU:    resource.MustParse("50m"),
+				coreV1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			Limits: coreV1.ResourceList{
+				coreV1.ResourceCPU:    resource.MustParse("500m"),
+				coreV1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+		},
+		KeyBits: 2048,
+	},
+	"production": {
+		Replicas: 2,
+		Resources: coreV1.ResourceRequirements{
+			Requests: coreV1.ResourceList{
+				coreV1.ResourceCPU:    resource.MustParse("250m"),
+				coreV1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+			Limits: coreV1.ResourceList{
+				coreV1.ResourceCPU:    resource.MustParse("1"),
+				coreV1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		AntiAffinity:        &enabled,
+		PodDisruptionBudget: &enabled,
+		KeyBits:             4096,
+		Expose:              ExposeLoadBalancer,
+	},
+}
+
+// Merge returns the profile with the fields override sets replaced
+func (p Profile) Merge(override Profile) Profile {
+	if override.Replicas != 0 {
+		p.Replicas = override.Replicas
+	}
+	if len(override.Resources.Requests) > 0 {
+		p.Resources.Requests = override.Resources.Requests
+	}
+	if len(override.Resources.Limits) > 0 {
+		p.Resources.Limits = override.Resources.Limits
+	}
+	if override.AntiAffinity != nil {
+		p.AntiAffinity = override.AntiAffinity
+	}
+	if override.PodDisruptionBudget != nil {
+		p.PodDisruptionBudget = override.PodDisruptionBudget
+	}
+	if override.KeyBits != 0 {
+		p.KeyBits = override.KeyBits
+	}
+	if override.Expose != "" {
+		p.Expose = override.Expose
+	}
+	p.Base = ""
+	return p
+}
+
+func mountsClaim(spec *coreV1.PodSpec) bool {
+	for _, volume := range spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func fillResources(list coreV1.ResourceList, defaults coreV1.ResourceList) coreV1.ResourceList {
+	if len(defaults) == 0 {
+		return list
+	}
+	if list == nil {
+		list = coreV1.ResourceList{}
+	}
+	for name, value := range defaults {
+		if _, ok := list[name]; !ok {
+			list[name] = value.DeepCopy()
+		}
+	}
+	return list
+}
+
+// ApplyProfile sets the replicas, resources and anti-affinity of the workloads and adds
+// the PodDisruptionBudgets the manifest lacks
+func ApplyProfile(objectList []runtime.Object, profile *Profile) []runtime.Object {
+	if profile == nil {
+		return objectList
+	}
+	budgets := make(map[string]bool)
+	for _, item := range objectList {
+		if pdb, ok := item.(*policyV1beta1.PodDisruptionBudget); ok && pdb.Spec.Selector != nil {
+			budgets[metaV1.FormatLabelSelector(pdb.Spec.Selector)] = true
+		}
+	}
+	var added []runtime.Object
+	for _, item := range objectList {
+		template, selector := PodTemplate(item)
+		if template == nil {
+			continue
+		}
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		// one MySQL pod owns the data, more would corrupt it
+		if profile.Replicas > 0 && !mountsClaim(&template.Spec) && !isBundledDatabase(accessor.GetName()) {
+			replicas := profile.Replicas
+			switch item.(type) {
+			case *appsV1.Deployment:
+				item.(*appsV1.Deployment).Spec.Replicas = &replicas
+			case *appsV1.StatefulSet:
+				item.(*appsV1.StatefulSet).Spec.Replicas = &replicas
+			}
+		}
+		for i := range template.Spec.Containers {
+			resources := &template.Spec.Containers[i].Resources
+			resources.Requests = fillResources(resources.Requests, profile.Resources.Requests)
+			resources.Limits = fillResources(resources.Limits, profile.Resources.Limits)
+		}
+		if profile.AntiAffinity != nil && *profile.AntiAffinity && selector != nil {
+			if template.Spec.Affinity == nil {
+				template.Spec.Affinity = &coreV1.Affinity{}
+			}
+			if template.Spec.Affinity.PodAntiAffinity == nil {
+				template.Spec.Affinity.PodAntiAffinity = &coreV1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []coreV1.WeightedPodAffinityTerm{
+						{
+							Weight: 100,
+							PodAffinityTerm: coreV1.PodAffinityTerm{
+								LabelSelector: selector.DeepCopy(),
+								TopologyKey:   coreV1.LabelHostname,
+							},
+						},
+					},
+				}
+			}
+		}
+		deployment, ok := item.(*appsV1.Deployment)
+		if !ok || profile.PodDisruptionBudget == nil || !*profile.PodDisruptionBudget || selector == nil || deployment.Spec.Replicas == nil || *deployment.Spec.Replicas < 2 {
+			continue
+		}
+		if budgets[metaV1.FormatLabelSelector(selector)] {
+			continue
+		}
+		minAvailable := intstr.FromInt(1)
+		added = append(added, &policyV1beta1.PodDisruptionBudget{
+			TypeMeta: metaV1.TypeMeta{
+				APIVersion: policyV1beta1.SchemeGroupVersion.String(),
+				Kind:       "PodDisruptionBudget",
+			},
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: deployment.Name,
+			},
+			Spec: policyV1beta1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     selector.DeepCopy(),
+			},
+		})
+	}
+	return append(objectList, added...)
+}
@@ -0,0 +1,157 @@
+package release
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	BundleManifestFile  = "manifest.yaml"
+	BundleReleaseFile   = "release.json"
+	BundleImagesFile    = "images.txt"
+	BundleChecksumsFile = "checksums.txt"
+)
+
+// Bundle is everything an install or update of one version needs, packed for offline use
+type Bundle struct {
+	Release  Release
+	Manifest []byte
+	Images   []string
+}
+
+// WriteBundle packs the bundle as a gzipped tar with a sha256sum style checksums file
+func WriteBundle(w io.Writer, bundle *Bundle) error {
+	releaseByte, err := json.MarshalIndent(bundle.Release, "", "  ")
+	if err != nil {
+		return err
+	}
+	var images bytes.Buffer
+	for _, image := range bundle.Images {
+		images.WriteString(image + "\n")
+	}
+	files := map[string][]byte{
+		BundleManifestFile: bundle.Manifest,
+		BundleReleaseFile:  releaseByte,
+		BundleImagesFile:   images.Bytes(),
+	}
+	names := []string{BundleManifestFile, BundleReleaseFile, BundleImagesFile}
+	var checksums bytes.Buffer
+	for _, name := range names {
+		digest := sha256.Sum256(files[name])
+		checksums.WriteString(fmt.Sprintf("%s  %s\n", hex.EncodeToString(digest[:]), name))
+	}
+	files[BundleChecksumsFile] = checksums.Bytes()
+	names = append(names, BundleChecksumsFile)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(files[name])),
+			ModTime: now,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadBundle unpacks a bundle file and verifies its checksums
+func ReadBundle(bundlePath string) (*Bundle, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("Read Bundle %s Failed: %s", bundlePath, err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Read Bundle %s Failed: %s", bundlePath, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("Read Bundle %s Failed: %s", bundlePath, err)
+		}
+		files[header.Name] = data
+	}
+	checksums, err := parseChecksums(files[BundleChecksumsFile])
+	if err != nil {
+		return nil, fmt.Errorf("Read Bundle %s Failed: %s", bundlePath, err)
+	}
+	for _, name := range []string{BundleManifestFile, BundleReleaseFile, BundleImagesFile} {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("Bundle %s Missing File: %s", bundlePath, name)
+		}
+		digest := sha256.Sum256(data)
+		if checksums[name] != hex.EncodeToString(digest[:]) {
+			return nil, fmt.Errorf("Bundle %s Checksum Mismatch: %s", bundlePath, name)
+		}
+	}
+	bundle := &Bundle{Manifest: files[BundleManifestFile]}
+	err = json.Unmarshal(files[BundleReleaseFile], &bundle.Release)
+	if err != nil {
+		return nil, fmt.Errorf("Read Bundle %s Failed: %s", bundlePath, err)
+	}
+	for _, line := range strings.Split(string(files[BundleImagesFile]), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			bundle.Images = append(bundle.Images, line)
+		}
+	}
+	sort.Strings(bundle.Images)
+	return bundle, nil
+}
+
+// parseChecksums reads "<sha256>  <name>" lines
+func parseChecksums(data []byte) (map[string]string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("%s not found", BundleChecksumsFile)
+	}
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid checksum line: %s", scanner.Text())
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return checksums, scanner.Err()
+}
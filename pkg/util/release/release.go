@@ -2,6 +2,8 @@ package release
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
 	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
 	"io/ioutil"
 	"net/http"
@@ -65,18 +67,53 @@ func GetLatestRelease() Release  {
 	return release
 }
 
-func Download(downloadUrl string) []byte {
-	t := terminal.NewTerminalPrint()
-	done := make(chan bool)
-	t.PrintLoadingOneLine(done, "Downloading Release...")
+// GetVersionRelease finds the release of a version, "latest" for the latest release
+func GetVersionRelease(version string) (Release, bool) {
+	if version == "latest" {
+		return GetLatestRelease(), true
+	}
+	for _, item := range GetRelease() {
+		if item.Name == version {
+			return item, true
+		}
+	}
+	return Release{}, false
+}
+
+// ManifestAsset returns the manifest asset of the release
+func (release Release) ManifestAsset() (Asset, bool) {
+	var manifest Asset
+	found := false
+	r, _ := regexp.Compile("^(.+).yaml$")
+	for _, asset := range release.Assets {
+		if r.MatchString(asset.Name) {
+			manifest = asset
+			found = true
+		}
+	}
+	return manifest, found
+}
+
+// FindAsset returns the first asset with one of the names
+func (release Release) FindAsset(names ...string) (Asset, bool) {
+	for _, name := range names {
+		for _, asset := range release.Assets {
+			if asset.Name == name {
+				return asset, true
+			}
+		}
+	}
+	return Asset{}, false
+}
+
+func fetch(downloadUrl string) ([]byte, error) {
 	res, err := http.Get(downloadUrl)
 	if err != nil {
-		t.PrintErrorOneLineWithExit(err)
+		return nil, err
 	}
 	defer res.Body.Close()
-	releaseByte, err := ioutil.ReadAll(res.Body)
-	done<-true
-	t.PrintSuccessOneLine("Download Complete")
-	t.LineEnd()
-	return releaseByte
-}
\ No newline at end of file
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("Download %s Failed: %s", downloadUrl, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,181 @@
+package release
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
+	"github.com/spf13/pflag"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+// ChecksumAssets are the names a release publishes the SHA-256 checksums of its assets under
+var ChecksumAssets = []string{"checksums.txt", "sha256sums.txt", "SHA256SUMS"}
+
+// VerifyOptions choose how a downloaded manifest is verified
+type VerifyOptions struct {
+	// SkipVerify accepts a manifest the release publishes no checksum for
+	SkipVerify bool
+	// PublicKey is the path of a PEM public key the manifest signature must verify against
+	PublicKey string
+}
+
+// VerifyFlags are the verification flags of the commands downloading a manifest
+func VerifyFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("verify", pflag.ExitOnError)
+	flags.Bool("skip-verify", false, "accept a downloaded manifest the release publishes no checksum for")
+	flags.String("verify-key", "", "a PEM public key the manifest signature must verify against")
+	return flags
+}
+
+// GetVerifyOptions reads the flags VerifyFlags adds
+func GetVerifyOptions(flags *pflag.FlagSet) (VerifyOptions, error) {
+	skipVerify, err := flags.GetBool("skip-verify")
+	if err != nil {
+		return VerifyOptions{}, err
+	}
+	publicKey, err := flags.GetString("verify-key")
+	if err != nil {
+		return VerifyOptions{}, err
+	}
+	if skipVerify && publicKey != "" {
+		return VerifyOptions{}, fmt.Errorf("Use only one of --skip-verify and --verify-key")
+	}
+	return VerifyOptions{SkipVerify: skipVerify, PublicKey: publicKey}, nil
+}
+
+// DownloadManifest downloads the manifest asset of the release and verifies it
+// against the published checksum and, with a public key, the detached signature <manifest>.sig
+func DownloadManifest(release Release, options VerifyOptions) ([]byte, error) {
+	t := terminal.NewTerminalPrint()
+	manifest, found := release.ManifestAsset()
+	if !found {
+		return nil, fmt.Errorf("Manifest Not Found: %s", release.Name)
+	}
+	done := make(chan bool)
+	t.PrintLoadingOneLine(done, "Downloading Release...")
+	fileByte, err := fetch(manifest.Download)
+	done <- true
+	if err != nil {
+		return nil, err
+	}
+	t.PrintSuccessOneLine("Download Complete")
+	t.LineEnd()
+	if options.SkipVerify {
+		t.PrintWarnOneLine("Verification Skipped: %s", manifest.Name)
+		t.LineEnd()
+		return fileByte, nil
+	}
+	digest, err := verifyChecksum(release, manifest, fileByte)
+	if err != nil {
+		return nil, err
+	}
+	t.PrintSuccessOneLine("Checksum Verified: sha256:%s", digest)
+	t.LineEnd()
+	if options.PublicKey != "" {
+		err := verifySignature(release, manifest, fileByte, options.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		t.PrintSuccessOneLine("Signature Verified: %s", options.PublicKey)
+		t.LineEnd()
+	}
+	return fileByte, nil
+}
+
+func verifyChecksum(release Release, manifest Asset, fileByte []byte) (string, error) {
+	sum := sha256.Sum256(fileByte)
+	digest := hex.EncodeToString(sum[:])
+	var expected string
+	if asset, found := release.FindAsset(manifest.Name + ".sha256"); found {
+		data, err := fetch(asset.Download)
+		if err != nil {
+			return "", err
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) == 0 {
+			return "", fmt.Errorf("Checksum Not Found: %s", asset.Name)
+		}
+		expected = strings.ToLower(fields[0])
+	} else if asset, found := release.FindAsset(ChecksumAssets...); found {
+		data, err := fetch(asset.Download)
+		if err != nil {
+			return "", err
+		}
+		checksums, err := parseChecksums(data)
+		if err != nil {
+			return "", fmt.Errorf("Read %s Failed: %s", asset.Name, err)
+		}
+		expected = checksums[manifest.Name]
+		if expected == "" {
+			return "", fmt.Errorf("Checksum Not Found: %s in %s", manifest.Name, asset.Name)
+		}
+	} else {
+		return "", fmt.Errorf("Release %s Publishes No Checksum, use --skip-verify to accept it unverified", release.Name)
+	}
+	if expected != digest {
+		return "", fmt.Errorf("Checksum Mismatch: %s is sha256:%s, expected sha256:%s", manifest.Name, digest, expected)
+	}
+	return digest, nil
+}
+
+func verifySignature(release Release, manifest Asset, fileByte []byte, publicKeyPath string) error {
+	keyByte, err := ioutil.ReadFile(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("Read Public Key Failed: %s", err)
+	}
+	block, _ := pem.Decode(keyByte)
+	if block == nil {
+		return fmt.Errorf("Read Public Key Failed: %s is not PEM encoded", publicKeyPath)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PublicKey(block.Bytes)
+		if rsaErr != nil {
+			return fmt.Errorf("Read Public Key Failed: %s", err)
+		}
+		publicKey = rsaKey
+	}
+	asset, found := release.FindAsset(manifest.Name + ".sig")
+	if !found {
+		return fmt.Errorf("Signature Not Found: %s.sig", manifest.Name)
+	}
+	signature, err := fetch(asset.Download)
+	if err != nil {
+		return err
+	}
+	// signatures may be published raw or base64 encoded
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature))); err == nil {
+		signature = decoded
+	}
+	digest := sha256.Sum256(fileByte)
+	valid := false
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(signature, &sig); err == nil {
+			valid = ecdsa.Verify(key, digest[:], sig.R, sig.S)
+		}
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, fileByte, signature)
+	default:
+		return fmt.Errorf("Unsupported Public Key Type: %T", publicKey)
+	}
+	if !valid {
+		return fmt.Errorf("Signature Verification Failed: %s", asset.Name)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func (t *Terminal) readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", fmt.Errorf("Input Closed")
+		}
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Prompt asks a question, an empty answer keeps defaultValue
+func (t *Terminal) Prompt(question string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Print(t.Spinning("? %s ", question), t.Yellow("(%s) ", defaultValue))
+	} else {
+		fmt.Print(t.Spinning("? %s ", question))
+	}
+	t.lastOneLineLen = 0
+	answer, err := t.readLine()
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return defaultValue, nil
+	}
+	return answer, nil
+}
+
+// PromptRequired asks until the answer is not empty
+func (t *Terminal) PromptRequired(question string, defaultValue string) (string, error) {
+	for {
+		answer, err := t.Prompt(question, defaultValue)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		t.PrintWarnOneLine("An Answer Is Required")
+		t.LineEnd()
+	}
+}
+
+// Select asks to choose one of the choices by number or value, an empty answer keeps defaultValue
+func (t *Terminal) Select(question string, choices []string, defaultValue string) (string, error) {
+	fmt.Println(t.Spinning("? %s", question))
+	for i, choice := range choices {
+		fmt.Printf("  %d) %s\n", i+1, choice)
+	}
+	for {
+		answer, err := t.Prompt("Choose", defaultValue)
+		if err != nil {
+			return "", err
+		}
+		if index, err := strconv.Atoi(answer); err == nil && index >= 1 && index <= len(choices) {
+			return choices[index-1], nil
+		}
+		for _, choice := range choices {
+			if answer == choice {
+				return choice, nil
+			}
+		}
+		t.PrintWarnOneLine("Choose A Number From 1 To %d", len(choices))
+		t.LineEnd()
+	}
+}
+
+// Confirm asks a yes or no question
+func (t *Terminal) Confirm(question string, defaultYes bool) (bool, error) {
+	defaultValue := "y/N"
+	if defaultYes {
+		defaultValue = "Y/n"
+	}
+	for {
+		answer, err := t.Prompt(question, defaultValue)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "y/n":
+			return defaultYes, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		t.PrintWarnOneLine("Answer yes or no")
+		t.LineEnd()
+	}
+}
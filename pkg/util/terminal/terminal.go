@@ -93,12 +93,6 @@ func (t *Terminal) PrintInfoOneLine(format string, a ...interface{}) {
 	t.lastOneLineLen = utf8.RuneCountInString(str) + 2
 }
 
-func (t *Terminal) PrintErrorOneLineWithPanic (a ...interface{})  {
-	t.PrintOneLine(t.errorString(fmt.Sprint(a...)))
-	t.LineEnd()
-	panic(a)
-}
-
 func (t *Terminal) PrintErrorOneLine (a ...interface{})  {
 	t.PrintOneLine(t.errorString(fmt.Sprint(a...)))
 	t.lastOneLineLen = 0
@@ -1,13 +1,21 @@
 package util
 
 import (
-	"github.com/funceasy/funceasy-cli/pkg/util/terminal"
-	"github.com/sirupsen/logrus"
+	"fmt"
+	"github.com/spf13/pflag"
+	coreV1 "k8s.io/api/core/v1"
+	rbacV1 "k8s.io/api/rbac/v1"
+	storageV1 "k8s.io/api/storage/v1"
+	apiextensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+	"sigs.k8s.io/yaml"
 	"strings"
-	"time"
 )
 
 func ParseK8sYaml(fileByte []byte) ([]runtime.Object, error) {
@@ -20,59 +28,179 @@ func ParseK8sYaml(fileByte []byte) ([]runtime.Object, error) {
 		decode := scheme.Codecs.UniversalDeserializer().Decode
 		obj, _, err := decode([]byte(fileStr), nil, nil)
 		if err != nil {
-			return nil, err
+			if !runtime.IsNotRegisteredError(err) {
+				return nil, err
+			}
+			// kinds the scheme does not know, such as custom resources, stay unstructured
+			jsonByte, err := yaml.YAMLToJSON([]byte(fileStr))
+			if err != nil {
+				return nil, err
+			}
+			obj, _, err = unstructured.UnstructuredJSONScheme.Decode(jsonByte, nil, nil)
+			if err != nil {
+				return nil, err
+			}
 		}
 		objectList = append(objectList, obj)
 	}
 	return objectList, nil
 }
 
-func SplitK8sYaml(fileByte []byte) []string {
-	readFileAsString := string(fileByte[:])
-	yamlFileSplits := strings.Split(readFileAsString, "---")
-	return yamlFileSplits
+// SetObjectsNamespace moves every namespace scoped object of the manifest into namespace
+func SetObjectsNamespace(objectList []runtime.Object, namespace string) {
+	for _, item := range objectList {
+		switch item.(type) {
+		case *coreV1.Namespace, *coreV1.PersistentVolume, *rbacV1.ClusterRole,
+			*v1beta1.CustomResourceDefinition, *apiextensionsV1.CustomResourceDefinition, *storageV1.StorageClass:
+			continue
+		case *rbacV1.ClusterRoleBinding:
+			setSubjectsNamespace(item.(*rbacV1.ClusterRoleBinding).Subjects, namespace)
+			continue
+		case *rbacV1.RoleBinding:
+			setSubjectsNamespace(item.(*rbacV1.RoleBinding).Subjects, namespace)
+		}
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		accessor.SetNamespace(namespace)
+	}
 }
 
-func GenerateDeleteCallback(function func(string, *metaV1.DeleteOptions) error, name string, options *metaV1.DeleteOptions) func() error {
-	return func() error {
-		err := function(name, options)
-		if err != nil {
-			return err
+func setSubjectsNamespace(subjects []rbacV1.Subject, namespace string) {
+	for i := range subjects {
+		if subjects[i].Kind == rbacV1.ServiceAccountKind {
+			subjects[i].Namespace = namespace
 		}
-		return nil
 	}
 }
 
-func GenerateUpdateCallback()  {
-	
+// ObjectReference identifies an object the CLI manages in the cluster
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func GetObjectReference(obj runtime.Object) ObjectReference {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		if kinds, _, err := scheme.Scheme.ObjectKinds(obj); err == nil && len(kinds) > 0 {
+			gvk = kinds[0]
+		}
+	}
+	reference := ObjectReference{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		reference.Namespace = accessor.GetNamespace()
+		reference.Name = accessor.GetName()
+	}
+	return reference
+}
+
+// MergeObjectReferences appends the references of extra missing in list
+func MergeObjectReferences(list []ObjectReference, extra []ObjectReference) []ObjectReference {
+	exists := make(map[ObjectReference]bool)
+	for _, item := range list {
+		exists[item] = true
+	}
+	for _, item := range extra {
+		if !exists[item] {
+			list = append(list, item)
+			exists[item] = true
+		}
+	}
+	return list
 }
 
-func Rollback(rollbackList []func() error, t *terminal.Terminal) {
-	t.PrintWarnOneLine("Start RollBack: %d Tasks", len(rollbackList))
-	t.LineEnd()
-	for _, cb := range rollbackList {
-		err := cb()
+// IsSubset reports whether every field set in desired has the same value in live,
+// fields only present in live such as server defaults are ignored. The nulls and empty
+// maps typed objects carry count as unset, a zero scalar or an empty list only matches
+// the same value or a missing live field.
+func IsSubset(desired interface{}, live interface{}) bool {
+	switch desired.(type) {
+	case map[string]interface{}:
+		if len(desired.(map[string]interface{})) == 0 {
+			return true
+		}
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range desired.(map[string]interface{}) {
+			if !IsSubset(value, liveMap[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		desiredList := desired.([]interface{})
+		liveList, ok := live.([]interface{})
+		if len(desiredList) == 0 {
+			return live == nil || ok && len(liveList) == 0
+		}
+		if !ok || len(desiredList) != len(liveList) {
+			return false
+		}
+		for index := range desiredList {
+			if !IsSubset(desiredList[index], liveList[index]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return true
+	case string, bool, int64, float64:
+		if live == nil {
+			// the server drops the zero values of omitted fields
+			return reflect.ValueOf(desired).IsZero()
+		}
+	}
+	return fmt.Sprint(desired) == fmt.Sprint(live)
+}
+
+// ChangedFlags collects the flags explicitly set on the command line
+func ChangedFlags(flags *pflag.FlagSet) map[string]string {
+	changed := make(map[string]string)
+	flags.Visit(func(flag *pflag.Flag) {
+		changed[flag.Name] = flag.Value.String()
+	})
+	return changed
+}
+
+// SetUnchangedFlags sets the flags the command line left unset, a name the flag set lacks is an error
+func SetUnchangedFlags(flags *pflag.FlagSet, values map[string]string) error {
+	for name, value := range values {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("Unknown Flag: %s", name)
+		}
+		if flag.Changed {
+			continue
+		}
+		err := flags.Set(name, value)
 		if err != nil {
-			t.PrintErrorOneLineWithPanic(err)
+			return fmt.Errorf("Flag %s: %s", name, err)
 		}
 	}
-	t.PrintSuccessOneLine("RollBack Complete: %d Tasks", len(rollbackList))
-	t.LineEnd()
+	return nil
+}
+
+func SplitK8sYaml(fileByte []byte) []string {
+	readFileAsString := string(fileByte[:])
+	yamlFileSplits := strings.Split(readFileAsString, "---")
+	return yamlFileSplits
 }
 
-func PollingCheck(do func(result chan string, done chan bool) error, interval time.Duration ,result chan string, done chan bool) {
-	for {
-		select {
-		case <-done:
-			return
-		case <-time.After(interval):
-			go func() {
-				if err := do(result, done); err != nil {
-					logrus.Error(err)
-					result<-"failed"
-					return
-				}
-			}()
+func GenerateDeleteCallback(function func(string, *metaV1.DeleteOptions) error, name string, options *metaV1.DeleteOptions) func() error {
+	return func() error {
+		err := function(name, options)
+		if err != nil {
+			return err
 		}
+		return nil
 	}
 }
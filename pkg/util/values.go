@@ -0,0 +1,511 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+)
+
+// Values patch the manifest objects before deploy. The top level keys are the lower case
+// kinds, the second level keys the manifest object names:
+//
+//	deployment:
+//	  funceasy-api:
+//	    replicas: 3
+//
+// A map value is merged into the object, lists of named items such as containers, env
+// or ports are merged by name and null removes a field. A list value is applied as a
+// JSON patch. Fields that are not top level object fields are resolved under spec.
+type Values map[string]interface{}
+
+// InstallValuesKey holds install flags by name, and the version, so a values file records a whole install:
+//
+//	install:
+//	  version: v0.1.0
+//	  storage-class: standard
+//	  expose: ingress
+//
+// The flags given on the command line win over them.
+const InstallValuesKey = "install"
+
+// InstallFlags returns the install section of the values as flag values
+func (values Values) InstallFlags() (map[string]string, error) {
+	flags := make(map[string]string)
+	section, ok := values[InstallValuesKey]
+	if !ok || section == nil {
+		return flags, nil
+	}
+	settings, ok := section.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Values %s: expect a map of flag names", InstallValuesKey)
+	}
+	for name, value := range settings {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("Values %s.%s: expect a single value", InstallValuesKey, name)
+		case nil:
+			flags[name] = ""
+		default:
+			flags[name] = fmt.Sprint(value)
+		}
+	}
+	return flags, nil
+}
+
+// LoadValuesFiles reads and merges the values files, later files win
+func LoadValuesFiles(paths []string) (Values, error) {
+	values := Values{}
+	for _, filePath := range paths {
+		fileByte, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("Read Values File Error: %s", err)
+		}
+		fileValues := Values{}
+		err = yaml.Unmarshal(fileByte, &fileValues)
+		if err != nil {
+			return nil, fmt.Errorf("Parse Values File %s Error: %s", filePath, err)
+		}
+		values = MergeValues(values, fileValues)
+	}
+	return values, nil
+}
+
+// SaveValuesFile writes the values as a values file
+func SaveValuesFile(filePath string, values Values) error {
+	fileByte, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filePath, fileByte, 0644)
+	if err != nil {
+		return fmt.Errorf("Write Values File Error: %s", err)
+	}
+	return nil
+}
+
+// MergeValues deep merges src into a copy of dst
+func MergeValues(dst Values, src Values) Values {
+	merged := mergePatch(map[string]interface{}(dst), map[string]interface{}(src))
+	if merged == nil {
+		return Values{}
+	}
+	return Values(merged.(map[string]interface{}))
+}
+
+// ApplyValues patches the manifest objects with the values and then the --set overrides
+// of the form kind.name.path=value. Object names may hold dots, a --set targets the object
+// with the longest name its key starts with.
+func ApplyValues(objectList []runtime.Object, values Values, sets []string) ([]runtime.Object, error) {
+	if len(values) == 0 && len(sets) == 0 {
+		return objectList, nil
+	}
+	objects := make([]*unstructured.Unstructured, 0, len(objectList))
+	for _, item := range objectList {
+		u, err := ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, u)
+	}
+	setTargets := make(map[string]string)
+	for _, set := range sets {
+		key, _, err := parseSet(set)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range objects {
+			target := strings.ToLower(u.GetKind()) + "." + u.GetName()
+			if strings.HasPrefix(key, target+".") && len(target) > len(setTargets[set]) {
+				setTargets[set] = target
+			}
+		}
+	}
+	applied := make(map[string]bool)
+	result := make([]runtime.Object, 0, len(objectList))
+	for index, item := range objectList {
+		u := objects[index]
+		kind := strings.ToLower(u.GetKind())
+		name := u.GetName()
+		target := kind + "." + name
+		changed := false
+		if kindValues, ok := values[kind].(map[string]interface{}); ok {
+			if patch, ok := kindValues[name]; ok {
+				applied[target] = true
+				changed = true
+				switch patch.(type) {
+				case []interface{}:
+					err := applyJSONPatch(u.Object, patch.([]interface{}))
+					if err != nil {
+						return nil, fmt.Errorf("Values %s: %s", target, err)
+					}
+				default:
+					merged := mergePatch(u.Object, resolvePatch(u.Object, patch))
+					u.Object = merged.(map[string]interface{})
+				}
+			}
+		}
+		for _, set := range sets {
+			key, value, err := parseSet(set)
+			if err != nil {
+				return nil, err
+			}
+			if setTargets[set] != target {
+				continue
+			}
+			applied[set] = true
+			changed = true
+			path := resolvePath(u.Object, strings.Split(strings.TrimPrefix(key, target+"."), "."))
+			err = setPath(u.Object, path, value)
+			if err != nil {
+				return nil, fmt.Errorf("--set %s: %s", set, err)
+			}
+		}
+		if !changed {
+			result = append(result, item)
+			continue
+		}
+		if _, ok := item.(*unstructured.Unstructured); ok {
+			result = append(result, u)
+			continue
+		}
+		typed, err := scheme.Scheme.New(u.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed)
+		if err != nil {
+			return nil, fmt.Errorf("Values %s: %s", target, err)
+		}
+		result = append(result, typed)
+	}
+	for kind, kindValues := range values {
+		if kind == InstallValuesKey {
+			continue
+		}
+		names, ok := kindValues.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Values %s: expect a map of object names", kind)
+		}
+		for name := range names {
+			if !applied[kind+"."+name] {
+				return nil, fmt.Errorf("Values Target Not Found: %s %s", kind, name)
+			}
+		}
+	}
+	for _, set := range sets {
+		if !applied[set] {
+			return nil, fmt.Errorf("--set Target Not Found: %s", set)
+		}
+	}
+	return result, nil
+}
+
+func parseSet(set string) (string, interface{}, error) {
+	parts := strings.SplitN(set, "=", 2)
+	if len(parts) != 2 || strings.Count(parts[0], ".") < 2 {
+		return "", nil, fmt.Errorf("--set %s: expect kind.name.path=value", set)
+	}
+	var value interface{}
+	err := yaml.Unmarshal([]byte(parts[1]), &value)
+	if err != nil {
+		return "", nil, fmt.Errorf("--set %s: %s", set, err)
+	}
+	key := parts[0]
+	dot := strings.Index(key, ".")
+	return strings.ToLower(key[:dot]) + key[dot:], normalizeNumbers(value), nil
+}
+
+// normalizeNumbers turns the float64 numbers of decoded YAML into int64 where they are whole
+func normalizeNumbers(value interface{}) interface{} {
+	switch value.(type) {
+	case float64:
+		number := value.(float64)
+		if number == float64(int64(number)) {
+			return int64(number)
+		}
+	case map[string]interface{}:
+		for key, item := range value.(map[string]interface{}) {
+			value.(map[string]interface{})[key] = normalizeNumbers(item)
+		}
+	case []interface{}:
+		for index, item := range value.([]interface{}) {
+			value.([]interface{})[index] = normalizeNumbers(item)
+		}
+	}
+	return value
+}
+
+var topLevelFields = map[string]bool{
+	"apiVersion": true,
+	"kind":       true,
+	"metadata":   true,
+	"spec":       true,
+	"data":       true,
+	"stringData": true,
+	"binaryData": true,
+	"type":       true,
+	"rules":      true,
+	"roleRef":    true,
+	"subjects":   true,
+}
+
+// resolvePath moves a path that does not start with a top level field under spec
+func resolvePath(obj map[string]interface{}, path []string) []string {
+	if _, hasSpec := obj["spec"]; hasSpec && !topLevelFields[path[0]] {
+		return append([]string{"spec"}, path...)
+	}
+	return path
+}
+
+func resolvePatch(obj map[string]interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	resolved := make(map[string]interface{})
+	spec := make(map[string]interface{})
+	for key, value := range normalizeNumbers(patchMap).(map[string]interface{}) {
+		if _, hasSpec := obj["spec"]; hasSpec && !topLevelFields[key] {
+			spec[key] = value
+		} else {
+			resolved[key] = value
+		}
+	}
+	if len(spec) > 0 {
+		resolved = mergePatch(resolved, map[string]interface{}{"spec": spec}).(map[string]interface{})
+	}
+	return resolved
+}
+
+// mergePatch merges patch into a copy of original with strategic merge semantics for
+// the lists of named items and null deleting a field
+func mergePatch(original interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		patchList, isList := patch.([]interface{})
+		originalList, originalIsList := original.([]interface{})
+		if isList && originalIsList && isNamedList(patchList) && isNamedList(originalList) {
+			return mergeNamedList(originalList, patchList)
+		}
+		return patch
+	}
+	originalMap, ok := original.(map[string]interface{})
+	result := make(map[string]interface{})
+	if ok {
+		for key, value := range originalMap {
+			result[key] = value
+		}
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = mergePatch(result[key], value)
+	}
+	return result
+}
+
+func isNamedList(list []interface{}) bool {
+	for _, item := range list {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := itemMap["name"]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeNamedList(original []interface{}, patch []interface{}) []interface{} {
+	result := make([]interface{}, len(original))
+	copy(result, original)
+	for _, item := range patch {
+		name := item.(map[string]interface{})["name"]
+		merged := false
+		for index, existing := range result {
+			if existing.(map[string]interface{})["name"] == name {
+				result[index] = mergePatch(existing, item)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// setPath sets value at path, list items are addressed by index or by name
+func setPath(obj map[string]interface{}, path []string, value interface{}) error {
+	var current interface{} = obj
+	for index, segment := range path {
+		last := index == len(path)-1
+		switch current.(type) {
+		case map[string]interface{}:
+			currentMap := current.(map[string]interface{})
+			if last {
+				currentMap[segment] = value
+				return nil
+			}
+			next, ok := currentMap[segment]
+			if !ok || next == nil {
+				next = make(map[string]interface{})
+				currentMap[segment] = next
+			}
+			current = next
+		case []interface{}:
+			currentList := current.([]interface{})
+			position, err := listIndex(currentList, segment)
+			if err != nil {
+				return err
+			}
+			if last {
+				currentList[position] = value
+				return nil
+			}
+			current = currentList[position]
+		default:
+			return fmt.Errorf("can not set %s on a scalar", strings.Join(path[:index+1], "."))
+		}
+	}
+	return nil
+}
+
+func listIndex(list []interface{}, segment string) (int, error) {
+	if position, err := strconv.Atoi(segment); err == nil {
+		if position < 0 || position >= len(list) {
+			return 0, fmt.Errorf("index %d out of range", position)
+		}
+		return position, nil
+	}
+	for position, item := range list {
+		if itemMap, ok := item.(map[string]interface{}); ok && itemMap["name"] == segment {
+			return position, nil
+		}
+	}
+	return 0, fmt.Errorf("no item named %s", segment)
+}
+
+// applyJSONPatch applies the add, replace and remove operations of a JSON patch
+func applyJSONPatch(obj map[string]interface{}, operations []interface{}) error {
+	for _, item := range operations {
+		operation, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid JSON patch operation: %v", item)
+		}
+		op, _ := operation["op"].(string)
+		pointer, _ := operation["path"].(string)
+		if !strings.HasPrefix(pointer, "/") {
+			return fmt.Errorf("invalid JSON patch path: %s", pointer)
+		}
+		var path []string
+		for _, segment := range strings.Split(pointer[1:], "/") {
+			segment = strings.Replace(segment, "~1", "/", -1)
+			path = append(path, strings.Replace(segment, "~0", "~", -1))
+		}
+		parent, err := getPath(obj, path[:len(path)-1])
+		if err != nil {
+			return err
+		}
+		key := path[len(path)-1]
+		value := normalizeNumbers(operation["value"])
+		switch parent.(type) {
+		case map[string]interface{}:
+			parentMap := parent.(map[string]interface{})
+			switch op {
+			case "add", "replace":
+				parentMap[key] = value
+			case "remove":
+				delete(parentMap, key)
+			default:
+				return fmt.Errorf("unsupported JSON patch op: %s", op)
+			}
+		case []interface{}:
+			parentList := parent.([]interface{})
+			var newList []interface{}
+			switch {
+			case op == "add" && key == "-":
+				newList = append(parentList, value)
+			case op == "add":
+				position, err := strconv.Atoi(key)
+				if err != nil || position < 0 || position > len(parentList) {
+					return fmt.Errorf("invalid JSON patch index: %s", pointer)
+				}
+				newList = append(append(append([]interface{}{}, parentList[:position]...), value), parentList[position:]...)
+			case op == "replace" || op == "remove":
+				position, err := strconv.Atoi(key)
+				if err != nil || position < 0 || position >= len(parentList) {
+					return fmt.Errorf("invalid JSON patch index: %s", pointer)
+				}
+				if op == "replace" {
+					parentList[position] = value
+					continue
+				}
+				newList = append(append([]interface{}{}, parentList[:position]...), parentList[position+1:]...)
+			default:
+				return fmt.Errorf("unsupported JSON patch op: %s", op)
+			}
+			grandParent, err := getPath(obj, path[:len(path)-2])
+			if err != nil {
+				return err
+			}
+			err = replaceChild(grandParent, path[len(path)-2], newList)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid JSON patch path: %s", pointer)
+		}
+	}
+	return nil
+}
+
+func getPath(obj interface{}, path []string) (interface{}, error) {
+	current := obj
+	for _, segment := range path {
+		switch current.(type) {
+		case map[string]interface{}:
+			next, ok := current.(map[string]interface{})[segment]
+			if !ok {
+				return nil, fmt.Errorf("path not found: %s", segment)
+			}
+			current = next
+		case []interface{}:
+			position, err := strconv.Atoi(segment)
+			list := current.([]interface{})
+			if err != nil || position < 0 || position >= len(list) {
+				return nil, fmt.Errorf("invalid index: %s", segment)
+			}
+			current = list[position]
+		default:
+			return nil, fmt.Errorf("path not found: %s", segment)
+		}
+	}
+	return current, nil
+}
+
+func replaceChild(parent interface{}, key string, child interface{}) error {
+	switch parent.(type) {
+	case map[string]interface{}:
+		parent.(map[string]interface{})[key] = child
+		return nil
+	case []interface{}:
+		position, err := strconv.Atoi(key)
+		list := parent.([]interface{})
+		if err != nil || position < 0 || position >= len(list) {
+			return fmt.Errorf("invalid index: %s", key)
+		}
+		list[position] = child
+		return nil
+	}
+	return fmt.Errorf("invalid JSON patch path")
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	batchV1beta1 "k8s.io/api/batch/v1beta1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PodTemplate returns the pod template and the pod selector of a workload,
+// nil for the objects running no pods
+func PodTemplate(obj runtime.Object) (*coreV1.PodTemplateSpec, *metaV1.LabelSelector) {
+	switch obj.(type) {
+	case *appsV1.Deployment:
+		workload := obj.(*appsV1.Deployment)
+		return &workload.Spec.Template, workload.Spec.Selector
+	case *appsV1.StatefulSet:
+		workload := obj.(*appsV1.StatefulSet)
+		return &workload.Spec.Template, workload.Spec.Selector
+	case *appsV1.DaemonSet:
+		workload := obj.(*appsV1.DaemonSet)
+		return &workload.Spec.Template, workload.Spec.Selector
+	case *appsV1.ReplicaSet:
+		workload := obj.(*appsV1.ReplicaSet)
+		return &workload.Spec.Template, workload.Spec.Selector
+	case *batchV1.Job:
+		workload := obj.(*batchV1.Job)
+		return &workload.Spec.Template, workload.Spec.Selector
+	case *batchV1beta1.CronJob:
+		workload := obj.(*batchV1beta1.CronJob)
+		return &workload.Spec.JobTemplate.Spec.Template, workload.Spec.JobTemplate.Spec.Selector
+	}
+	return nil, nil
+}
+
+// PodReplicas returns how many pods of the template a workload asks for,
+// DaemonSets run one on each of the nodes
+func PodReplicas(obj runtime.Object, nodes int32) int32 {
+	replicas := func(value *int32) int32 {
+		if value == nil {
+			return 1
+		}
+		return *value
+	}
+	switch obj.(type) {
+	case *appsV1.Deployment:
+		return replicas(obj.(*appsV1.Deployment).Spec.Replicas)
+	case *appsV1.StatefulSet:
+		return replicas(obj.(*appsV1.StatefulSet).Spec.Replicas)
+	case *appsV1.DaemonSet:
+		return nodes
+	case *appsV1.ReplicaSet:
+		return replicas(obj.(*appsV1.ReplicaSet).Spec.Replicas)
+	case *batchV1.Job:
+		return replicas(obj.(*batchV1.Job).Spec.Parallelism)
+	case *batchV1beta1.CronJob:
+		return replicas(obj.(*batchV1beta1.CronJob).Spec.JobTemplate.Spec.Parallelism)
+	}
+	return 0
+}